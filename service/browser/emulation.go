@@ -0,0 +1,189 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/device"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const defaultGeolocationAccuracy = 1
+
+// devices are the supported device presets, looked up by their case insensitive name
+var devices = []chromedp.Device{
+	device.IPhoneSE, device.IPhoneSElandscape,
+	device.IPhoneX, device.IPhoneXlandscape,
+	device.IPhone12, device.IPhone12landscape,
+	device.IPhone13, device.IPhone13landscape,
+	device.IPhone14, device.IPhone14landscape,
+	device.IPhone15, device.IPhone15landscape,
+	device.IPhone15ProMax, device.IPhone15ProMaxlandscape,
+	device.Pixel5, device.Pixel5landscape,
+	device.GalaxyS9, device.GalaxyS9landscape,
+	device.IPad, device.IPadlandscape,
+	device.IPadMini, device.IPadMinilandscape,
+	device.IPadPro, device.IPadProlandscape,
+	device.GalaxyTabS4, device.GalaxyTabS4landscape,
+}
+
+var colorSchemes = map[string]bool{
+	"light":         true,
+	"dark":          true,
+	"no-preference": true,
+}
+
+// Emulation returns the tasks applying the flow browser config to a new tab
+func Emulation(conf *config.BrowserConfig) (chromedp.Tasks, error) {
+	var tasks chromedp.Tasks
+	if conf == nil {
+		return tasks, nil
+	}
+
+	userAgent := conf.UserAgent
+
+	if conf.Device != "" {
+		d, err := findDevice(conf.Device)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, chromedp.Emulate(d))
+
+		if userAgent == "" {
+			userAgent = d.Device().UserAgent
+		}
+	}
+
+	if conf.Viewport != nil {
+		viewportTasks, err := viewport(conf.Viewport)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, viewportTasks...)
+	}
+
+	acceptLanguage := conf.AcceptLanguage
+	if acceptLanguage == "" {
+		acceptLanguage = conf.Locale
+	}
+
+	if conf.UserAgent != "" || acceptLanguage != "" {
+		tasks = append(tasks, userAgentOverride(userAgent, acceptLanguage))
+	}
+
+	if conf.Locale != "" {
+		tasks = append(tasks, emulation.SetLocaleOverride().WithLocale(conf.Locale))
+	}
+
+	if conf.Timezone != "" {
+		if _, err := time.LoadLocation(conf.Timezone); err != nil {
+			return nil, errors.Wrapf(err, "invalid timezone '%s'", conf.Timezone)
+		}
+		tasks = append(tasks, emulation.SetTimezoneOverride(conf.Timezone))
+	}
+
+	if conf.Geolocation != nil {
+		geolocationTasks, err := geolocation(conf.Geolocation)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, geolocationTasks...)
+	}
+
+	if conf.ColorScheme != "" {
+		if !colorSchemes[conf.ColorScheme] {
+			return nil, errors.Errorf("invalid color scheme '%s'", conf.ColorScheme)
+		}
+		tasks = append(tasks, emulation.SetEmulatedMedia().WithFeatures([]*emulation.MediaFeature{
+			{Name: "prefers-color-scheme", Value: conf.ColorScheme},
+		}))
+	}
+
+	return tasks, nil
+}
+
+func findDevice(name string) (chromedp.Device, error) {
+	for _, d := range devices {
+		if strings.EqualFold(d.Device().Name, name) {
+			return d, nil
+		}
+	}
+
+	return nil, errors.Errorf("unknown device '%s'", name)
+}
+
+func viewport(conf *config.ViewportConfig) (chromedp.Tasks, error) {
+	if conf.Width <= 0 || conf.Height <= 0 {
+		return nil, errors.Errorf("invalid viewport %dx%d", conf.Width, conf.Height)
+	}
+
+	return chromedp.Tasks{
+		emulation.SetDeviceMetricsOverride(conf.Width, conf.Height, conf.DeviceScaleFactor, conf.Mobile),
+		emulation.SetTouchEmulationEnabled(conf.Touch),
+	}, nil
+}
+
+// userAgentOverride sets the user agent and the Accept-Language header, the browser
+// user agent is kept when only the language is set
+func userAgentOverride(userAgent string, acceptLanguage string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if userAgent == "" {
+			var err error
+			_, _, _, userAgent, _, err = browser.GetVersion().Do(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed getting the browser user agent")
+			}
+		}
+
+		override := emulation.SetUserAgentOverride(userAgent)
+		if acceptLanguage != "" {
+			override = override.WithAcceptLanguage(acceptLanguage)
+		}
+
+		return override.Do(ctx)
+	})
+}
+
+// geolocation overrides the position and grants the geolocation permission to the tab browser context
+func geolocation(conf *config.GeolocationConfig) (chromedp.Tasks, error) {
+	if conf.Latitude < -90 || conf.Latitude > 90 || conf.Longitude < -180 || conf.Longitude > 180 {
+		return nil, errors.Errorf("invalid geolocation %f,%f", conf.Latitude, conf.Longitude)
+	}
+
+	accuracy := conf.Accuracy
+	if accuracy == 0 {
+		accuracy = defaultGeolocationAccuracy
+	}
+
+	return chromedp.Tasks{
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			c := chromedp.FromContext(ctx)
+			return browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation}).
+				WithBrowserContextID(c.BrowserContextID).
+				Do(cdp.WithExecutor(ctx, c.Browser))
+		}),
+		emulation.SetGeolocationOverride().
+			WithLatitude(conf.Latitude).
+			WithLongitude(conf.Longitude).
+			WithAccuracy(accuracy),
+	}, nil
+}
+
+// FlagsKey returns a stable representation of the Chrome flags, flows with the same flags share browsers
+func FlagsKey(flags map[string]interface{}) string {
+	keys := make([]string, 0, len(flags))
+	for name, value := range flags {
+		keys = append(keys, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
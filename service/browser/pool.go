@@ -0,0 +1,504 @@
+package browser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/service"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	healthCheckTimeout    = 10 * time.Second
+	remoteConnectAttempts = 3
+	localPoolName         = "local"
+)
+
+// remoteConnectBackoff is the wait before the next remote connection attempt, times the attempt
+var remoteConnectBackoff = time.Second
+
+var ErrPoolStopped = errors.New("browser pool is stopped")
+
+// PoolInterface shares long lived browser processes between the flow runs,
+// every run gets its own tab in a new incognito browser context
+type PoolInterface interface {
+	// Start runs the browsers health checks, browsers are launched on their first tab
+	Start()
+	Stop()
+	// NewTab blocks until a tab is available, the returned cancel func closes the tab
+	// and its browser context and must be called once the run is done
+	NewTab(ctx context.Context) (context.Context, context.CancelFunc, error)
+}
+
+// pooledBrowser is a browser process and the tabs opened on it
+type pooledBrowser struct {
+	id     int
+	ctx    context.Context
+	cancel context.CancelFunc
+	tabs   int // open tabs
+	runs   int // tabs opened since the browser started
+
+	starting bool          // set while the browser is started, ctx and cancel are then unset
+	ready    chan struct{} // closed once the browser started or failed starting
+}
+
+// alive returns false once the browser process exited or lost its connection
+func (b *pooledBrowser) alive() bool {
+	return b.ctx.Err() == nil
+}
+
+type poolImpl struct {
+	rootCtx        context.Context
+	remoteURL      string // empty to launch local browsers
+	flags          map[string]interface{}
+	testerSettings *config.TesterSettings
+	metricsService service.MetricsServiceInterface
+
+	slots chan struct{} // one per tab the pool can open
+	stop  chan struct{}
+	wg    sync.WaitGroup
+
+	lock      sync.Mutex
+	browsers  []*pooledBrowser // nil entries are started on the next tab
+	retiring  map[*pooledBrowser]struct{}
+	nextID    int
+	stopped   bool
+	tabsInUse int
+}
+
+// NewPool creates a pool of local browsers launched with the extra flags, or of connections
+// to the remote browser when remoteURL is set (see RemoteURL)
+func NewPool(
+	rootCtx context.Context,
+	remoteURL string,
+	flags map[string]interface{},
+	testerSettings *config.TesterSettings,
+	metricsService service.MetricsServiceInterface,
+) PoolInterface {
+	return &poolImpl{
+		rootCtx:        rootCtx,
+		remoteURL:      remoteURL,
+		flags:          flags,
+		testerSettings: testerSettings,
+		metricsService: metricsService,
+		slots:          make(chan struct{}, testerSettings.BrowserPoolSize*testerSettings.BrowserMaxTabs),
+		stop:           make(chan struct{}),
+		browsers:       make([]*pooledBrowser, testerSettings.BrowserPoolSize),
+		retiring:       map[*pooledBrowser]struct{}{},
+	}
+}
+
+func (p *poolImpl) Start() {
+	p.reportUsage()
+
+	p.wg.Add(1)
+	go p.healthChecks()
+}
+
+// Stop closes all the browsers, open tabs are closed with them
+func (p *poolImpl) Stop() {
+	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
+		return
+	}
+	p.stopped = true
+	close(p.stop)
+	p.lock.Unlock()
+
+	p.wg.Wait()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// browsers being started are closed by their starter once it sees the pool stopped
+	for i, b := range p.browsers {
+		if b != nil && !b.starting {
+			b.cancel()
+		}
+		p.browsers[i] = nil
+	}
+
+	for b := range p.retiring {
+		b.cancel()
+		delete(p.retiring, b)
+	}
+}
+
+func (p *poolImpl) NewTab(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	waitStart := time.Now()
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, errors.Wrap(ctx.Err(), "failed waiting for a browser tab")
+	case <-p.stop:
+		return nil, nil, ErrPoolStopped
+	}
+
+	ms := float64(time.Since(waitStart).Nanoseconds()) / 1e6
+	if err := p.metricsService.ReportBrowserPoolWait(p.rootCtx, p.name(), ms); err != nil {
+		log.WithError(err).Error("failed reporting browser pool wait")
+	}
+
+	b, err := p.acquireBrowser(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, nil, err
+	}
+	p.reportUsage()
+
+	tabCtx, tabCancel := chromedp.NewContext(b.ctx, chromedp.WithNewBrowserContext())
+
+	// open the tab before returning it, a flow timeout on the first run would close it
+	err = chromedp.Run(tabCtx)
+	if err != nil {
+		tabCancel()
+		p.releaseBrowser(b)
+		return nil, nil, errors.Wrap(err, "failed opening browser tab")
+	}
+
+	var once sync.Once
+	return tabCtx, func() {
+		once.Do(func() {
+			tabCancel()
+			p.releaseBrowser(b)
+		})
+	}, nil
+}
+
+// acquireBrowser returns the running browser with the fewest open tabs. A new browser is only
+// started when no running browser has a free tab, it is started outside the lock so other tabs
+// are acquired and released meanwhile. A browser which reached its maximum runs is retired
+func (p *poolImpl) acquireBrowser(ctx context.Context) (*pooledBrowser, error) {
+	for {
+		p.lock.Lock()
+		if p.stopped {
+			p.lock.Unlock()
+			return nil, ErrPoolStopped
+		}
+
+		selected, free, starting := p.selectBrowser()
+
+		if selected != -1 {
+			b := p.useBrowser(selected)
+			p.lock.Unlock()
+			return b, nil
+		}
+
+		if free != -1 {
+			return p.startPooledBrowser(free)
+		}
+		p.lock.Unlock()
+
+		// cannot happen while the slots limit the tabs to the pool capacity
+		if starting == nil {
+			return nil, errors.New("no browser available")
+		}
+
+		// the free tabs are on a browser being started
+		select {
+		case <-starting.ready:
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "failed waiting for a browser to start")
+		}
+	}
+}
+
+// selectBrowser returns the running browser with the fewest open tabs and a free tab, a free
+// pool entry and a browser being started, -1 and nil when none. Crashed browsers are retired,
+// must be called with the lock held
+func (p *poolImpl) selectBrowser() (int, int, *pooledBrowser) {
+	selected, free := -1, -1
+	var starting *pooledBrowser
+	for i, b := range p.browsers {
+		if b != nil && !b.starting && !b.alive() {
+			log.WithField("browser", b.id).Warn("browser crashed or lost its connection, recycling")
+			p.retire(i, service.BrowserRecycleUnhealthy)
+			b = nil
+		}
+
+		switch {
+		case b == nil:
+			if free == -1 {
+				free = i
+			}
+		case b.starting:
+			starting = b
+		case b.tabs < p.testerSettings.BrowserMaxTabs && (selected == -1 || b.tabs < p.browsers[selected].tabs):
+			selected = i
+		}
+	}
+
+	return selected, free, starting
+}
+
+// startPooledBrowser reserves the pool entry, starts the browser without the lock and
+// publishes it. Must be called with the lock held, the lock is released on return
+func (p *poolImpl) startPooledBrowser(i int) (*pooledBrowser, error) {
+	p.nextID++
+	b := &pooledBrowser{
+		id:       p.nextID,
+		starting: true,
+		ready:    make(chan struct{}),
+	}
+	p.browsers[i] = b
+	p.lock.Unlock()
+
+	ctx, cancel, err := p.startBrowser(b.id)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	defer close(b.ready)
+
+	b.starting = false
+	if err == nil && p.stopped {
+		cancel()
+		err = ErrPoolStopped
+	}
+	if err != nil {
+		if p.browsers[i] == b {
+			p.browsers[i] = nil
+		}
+
+		// a tab may have been closed on a running browser meanwhile
+		if selected, _, _ := p.selectBrowser(); selected != -1 && !p.stopped {
+			log.WithField("pool", p.name()).WithError(err).Warn("failed starting browser, using a running browser")
+			return p.useBrowser(selected), nil
+		}
+
+		return nil, err
+	}
+
+	b.ctx = ctx
+	b.cancel = cancel
+
+	return p.useBrowser(i), nil
+}
+
+// useBrowser opens a tab on the browser, must be called with the lock held
+func (p *poolImpl) useBrowser(i int) *pooledBrowser {
+	b := p.browsers[i]
+	b.tabs++
+	b.runs++
+	p.tabsInUse++
+
+	if p.testerSettings.BrowserRecycleRuns > 0 && b.runs >= p.testerSettings.BrowserRecycleRuns {
+		p.retire(i, service.BrowserRecycleRuns)
+	}
+
+	return b
+}
+
+// releaseBrowser closes a retired browser once its last tab is closed
+func (p *poolImpl) releaseBrowser(b *pooledBrowser) {
+	p.lock.Lock()
+	b.tabs--
+	p.tabsInUse--
+	if _, ok := p.retiring[b]; ok && b.tabs == 0 {
+		b.cancel()
+		delete(p.retiring, b)
+	}
+	p.lock.Unlock()
+
+	<-p.slots
+	p.reportUsage()
+}
+
+// retire removes the browser from the pool, it is closed once its open tabs are closed.
+// Must be called with the lock held
+func (p *poolImpl) retire(i int, reason string) {
+	b := p.browsers[i]
+	p.browsers[i] = nil
+
+	if b.tabs == 0 || !b.alive() {
+		b.cancel()
+	} else {
+		p.retiring[b] = struct{}{}
+	}
+
+	if err := p.metricsService.ReportBrowserRecycle(p.rootCtx, p.name(), reason); err != nil {
+		log.WithError(err).Error("failed reporting browser recycle")
+	}
+}
+
+// startBrowser launches a new browser process or connects to the remote browser,
+// it is called without the lock as starting a browser takes seconds
+func (p *poolImpl) startBrowser(id int) (context.Context, context.CancelFunc, error) {
+	if p.remoteURL == "" {
+		return p.connectBrowser(id)
+	}
+
+	// the remote browser may be restarting, retry before failing the run
+	var err error
+	for attempt := 1; attempt <= remoteConnectAttempts; attempt++ {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		ctx, cancel, err = p.connectBrowser(id)
+		if err == nil {
+			return ctx, cancel, nil
+		}
+
+		log.WithField("remote", p.remoteURL).WithError(err).Warnf("failed connecting to remote browser, attempt %d", attempt)
+		if attempt == remoteConnectAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * remoteConnectBackoff):
+		case <-p.stop:
+			return nil, nil, err
+		case <-p.rootCtx.Done():
+			return nil, nil, err
+		}
+	}
+
+	return nil, nil, err
+}
+
+func (p *poolImpl) connectBrowser(id int) (context.Context, context.CancelFunc, error) {
+	logger := log.WithFields(log.Fields{
+		"browser": id,
+		"pool":    p.name(),
+	})
+
+	var allocCtx context.Context
+	var allocCancel context.CancelFunc
+	if p.remoteURL != "" {
+		allocCtx, allocCancel = chromedp.NewRemoteAllocator(p.rootCtx, p.remoteURL)
+	} else {
+		// create the flags for the headless browser
+		opts := append(chromedp.DefaultExecAllocatorOptions[:],
+			chromedp.Flag("ignore-certificate-errors", true),
+			chromedp.UserAgent("Firefox/80"),
+		)
+
+		if p.testerSettings.ShowDebugBrowser {
+			opts = append(opts, chromedp.Flag("headless", false))
+		}
+
+		for name, value := range p.flags {
+			opts = append(opts, chromedp.Flag(name, value))
+		}
+
+		// create a allocator with the new flags
+		allocCtx, allocCancel = chromedp.NewExecAllocator(p.rootCtx, opts...)
+	}
+
+	// create browser context with the allocator and logging
+	browserCtx, browserCancel := chromedp.NewContext(
+		allocCtx,
+		chromedp.WithDebugf(logger.Debugf),
+		chromedp.WithLogf(logger.Infof),
+		chromedp.WithErrorf(logger.Errorf))
+
+	cancel := func() {
+		// call all created cancel func
+		browserCancel()
+		allocCancel()
+	}
+
+	err := chromedp.Run(browserCtx)
+	if err != nil {
+		cancel()
+		if p.remoteURL != "" {
+			return nil, nil, errors.Wrapf(err, "failed connecting to remote browser at %s", p.remoteURL)
+		}
+		return nil, nil, errors.Wrap(err, "failed starting browser")
+	}
+
+	logger.Info("started browser")
+
+	return browserCtx, cancel, nil
+}
+
+// healthChecks periodically recycles the browsers which do not respond
+func (p *poolImpl) healthChecks() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.testerSettings.ParsedBrowserHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkBrowsers()
+		}
+	}
+}
+
+func (p *poolImpl) checkBrowsers() {
+	p.lock.Lock()
+	browsers := make([]*pooledBrowser, len(p.browsers))
+	for i, b := range p.browsers {
+		if b != nil && !b.starting {
+			browsers[i] = b
+		}
+	}
+	p.lock.Unlock()
+
+	for i, b := range browsers {
+		if b == nil {
+			continue
+		}
+
+		err := ping(b)
+		if err == nil {
+			continue
+		}
+
+		log.WithField("browser", b.id).WithError(err).Warn("browser health check failed, recycling")
+
+		p.lock.Lock()
+		// the browser may have been recycled while it was checked
+		if p.browsers[i] == b {
+			p.retire(i, service.BrowserRecycleUnhealthy)
+		}
+		p.lock.Unlock()
+	}
+}
+
+// ping checks the browser responds to the devtools protocol
+func ping(b *pooledBrowser) error {
+	if !b.alive() {
+		return errors.New("browser is not running")
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, healthCheckTimeout)
+	defer cancel()
+
+	_, _, _, _, _, err := browser.GetVersion().Do(cdp.WithExecutor(ctx, chromedp.FromContext(b.ctx).Browser))
+
+	return err
+}
+
+// name is the pool metrics label
+func (p *poolImpl) name() string {
+	if p.remoteURL != "" {
+		return p.remoteURL
+	}
+
+	if len(p.flags) > 0 {
+		return localPoolName + " " + FlagsKey(p.flags)
+	}
+
+	return localPoolName
+}
+
+func (p *poolImpl) reportUsage() {
+	p.lock.Lock()
+	inUse := p.tabsInUse
+	p.lock.Unlock()
+
+	err := p.metricsService.ReportBrowserPoolUsage(p.rootCtx, p.name(), inUse, cap(p.slots))
+	if err != nil {
+		log.WithError(err).Error("failed reporting browser pool usage")
+	}
+}
@@ -0,0 +1,39 @@
+package browser
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const devtoolsBrowserPath = "/devtools/browser"
+
+// RemoteURL validates the address of a remote browser devtools endpoint, either its websocket URL
+// or a host:port address whose websocket URL is discovered through its /json/version endpoint
+func RemoteURL(address string) (string, error) {
+	if !strings.Contains(address, "://") {
+		address = "ws://" + address
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid remote browser address '%s'", address)
+	}
+
+	switch u.Scheme {
+	case "ws", "wss", "http", "https":
+	default:
+		return "", errors.Errorf("invalid remote browser address '%s': unsupported scheme '%s'", address, u.Scheme)
+	}
+
+	if u.Host == "" {
+		return "", errors.Errorf("invalid remote browser address '%s': missing host", address)
+	}
+
+	if strings.TrimSuffix(u.Path, "/") == devtoolsBrowserPath {
+		return "", errors.Errorf("invalid remote browser address '%s': missing browser id", address)
+	}
+
+	return address, nil
+}
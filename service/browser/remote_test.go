@@ -0,0 +1,175 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/service"
+)
+
+func TestRemoteURL(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+		wantErr bool
+	}{
+		{"ws://chrome:9222/devtools/browser/abc", "ws://chrome:9222/devtools/browser/abc", false},
+		{"wss://chrome.example.com/devtools/browser/abc", "wss://chrome.example.com/devtools/browser/abc", false},
+		{"chrome:9222", "ws://chrome:9222", false},
+		{"127.0.0.1:9222", "ws://127.0.0.1:9222", false},
+		{"http://chrome:9222", "http://chrome:9222", false},
+		{"ftp://chrome:9222", "", true},
+		{"ws://", "", true},
+		{"ws://chrome:9222/devtools/browser/", "", true},
+		{"ws://chrome:9222/%zz", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := RemoteURL(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %s got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// fakeMetrics ignores the pool metrics
+type fakeMetrics struct {
+	service.MetricsServiceInterface
+}
+
+func (m *fakeMetrics) ReportBrowserPoolUsage(context.Context, string, int, int) error { return nil }
+func (m *fakeMetrics) ReportBrowserPoolWait(context.Context, string, float64) error   { return nil }
+func (m *fakeMetrics) ReportBrowserRecycle(context.Context, string, string) error     { return nil }
+
+// devtoolsStub answers /json/version with a websocket URL it does not upgrade,
+// it records the requested paths
+type devtoolsStub struct {
+	*httptest.Server
+	lock  sync.Mutex
+	paths []string
+}
+
+func newDevtoolsStub(t *testing.T) *devtoolsStub {
+	t.Helper()
+
+	stub := &devtoolsStub{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.lock.Lock()
+		stub.paths = append(stub.paths, r.URL.Path)
+		stub.lock.Unlock()
+
+		if r.URL.Path == "/json/version" {
+			fmt.Fprintf(w, `{"Browser": "HeadlessChrome", "webSocketDebuggerUrl": "ws://%s/devtools/browser/stub"}`, r.Host)
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+func (s *devtoolsStub) requestedPaths() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string{}, s.paths...)
+}
+
+func newRemoteTestPool(t *testing.T, address string) PoolInterface {
+	t.Helper()
+
+	backoff := remoteConnectBackoff
+	remoteConnectBackoff = time.Millisecond
+	t.Cleanup(func() { remoteConnectBackoff = backoff })
+
+	remoteURL, err := RemoteURL(address)
+	if err != nil {
+		t.Fatalf("invalid remote address: %v", err)
+	}
+
+	settings := &config.TesterSettings{BrowserPoolSize: 1, BrowserMaxTabs: 1}
+	pool := NewPool(context.Background(), remoteURL, nil, settings, &fakeMetrics{})
+	t.Cleanup(pool.Stop)
+
+	return pool
+}
+
+func newTabError(t *testing.T, pool PoolInterface) error {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, closeTab, err := pool.NewTab(ctx)
+	if err == nil {
+		closeTab()
+		t.Fatal("expected the connection to the stub to fail")
+	}
+
+	return err
+}
+
+func TestRemotePoolDiscoversWebsocketURL(t *testing.T) {
+	stub := newDevtoolsStub(t)
+	address := strings.TrimPrefix(stub.URL, "http://")
+
+	err := newTabError(t, newRemoteTestPool(t, address))
+	if !strings.Contains(err.Error(), "failed connecting to remote browser at ws://"+address) {
+		t.Fatalf("expected a remote connection error got %v", err)
+	}
+
+	// the host:port address is resolved by /json/version then the websocket URL is dialed
+	paths := stub.requestedPaths()
+	if len(paths) < 2 || paths[0] != "/json/version" || paths[1] != "/devtools/browser/stub" {
+		t.Fatalf("expected the websocket URL discovery got requests %v", paths)
+	}
+}
+
+func TestRemotePoolWebsocketURL(t *testing.T) {
+	stub := newDevtoolsStub(t)
+	address := strings.Replace(stub.URL, "http://", "ws://", 1) + "/devtools/browser/given"
+
+	err := newTabError(t, newRemoteTestPool(t, address))
+	if !strings.Contains(err.Error(), "failed connecting to remote browser at "+address) {
+		t.Fatalf("expected a remote connection error got %v", err)
+	}
+
+	// a websocket URL is dialed as is
+	for _, path := range stub.requestedPaths() {
+		if path != "/devtools/browser/given" {
+			t.Fatalf("expected only the given websocket URL to be requested got %v", stub.requestedPaths())
+		}
+	}
+	if len(stub.requestedPaths()) != remoteConnectAttempts {
+		t.Fatalf("expected %d connection attempts got %v", remoteConnectAttempts, stub.requestedPaths())
+	}
+}
+
+func TestRemotePoolUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening: %v", err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	err = newTabError(t, newRemoteTestPool(t, address))
+	if !strings.Contains(err.Error(), "failed connecting to remote browser at ws://"+address) {
+		t.Fatalf("expected a remote connection error got %v", err)
+	}
+}
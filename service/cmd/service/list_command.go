@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// listCommand prints the configured flows with their schedule and steps
+func listCommand(args []string) int {
+	flags, logLevel := newFlagSet("list", "",
+		"Lists the flows with their schedule and their setup, steps and teardown", log.WarnLevel)
+	if err := parseFlags(flags, logLevel, args); err != nil {
+		return usageExitCode(err)
+	}
+
+	configReader := config.NewBlackboxConfigReader()
+	testerSettings, err := configReader.LoadTesterSettings()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	testerConfig, err := configReader.LoadTesterConfig(testerSettings)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	names := make([]string, 0, len(testerConfig.Flows))
+	for name := range testerConfig.Flows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FLOW\tSCHEDULE\tPHASE\tSTEP\tTYPE")
+	for _, name := range names {
+		flow := testerConfig.Flows[name]
+		fmt.Fprintf(w, "%s\t%s\t\t\t\n", name, flow.Config.Frequency)
+
+		for _, group := range []struct {
+			phase string
+			steps []config.FlowStep
+		}{
+			{"setup", flow.Setup},
+			{"step", flow.Steps},
+			{"teardown", flow.Teardown},
+		} {
+			for _, step := range group.steps {
+				stepType := "undefined"
+				if definition, ok := testerConfig.Definitions[step.Step]; ok {
+					stepType = definition.Type
+				}
+				fmt.Fprintf(w, "\t\t%s\t%s\t%s\n", group.phase, step.Step, stepType)
+			}
+		}
+	}
+	_ = w.Flush()
+
+	return exitSuccess
+}
@@ -2,80 +2,139 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"os"
+	"strings"
 
-	"contrib.go.opencensus.io/exporter/prometheus"
 	"github.com/orensho/thin-slack-blackbox-tester/service/config"
-	"github.com/orensho/thin-slack-blackbox-tester/service/server"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
+	"github.com/orensho/thin-slack-blackbox-tester/service/report"
 	"github.com/orensho/thin-slack-blackbox-tester/service/service"
 	"github.com/orensho/thin-slack-blackbox-tester/service/steps"
 	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
 	log "github.com/sirupsen/logrus"
+)
 
-	"net/http"
-	"os"
+const usage = `Usage: service [command] [flags]
+
+Commands:
+  serve     run the flows on their schedule and serve the API (default)
+  run       run flows once, exits non-zero when a flow fails
+  validate  validate the settings and the config file
+  list      list the flows and their steps
+
+Settings are read from the environment variables, run 'service <command> -h' for the command flags`
+
+// exit codes of the one-shot commands
+const (
+	exitSuccess = 0
+	exitFailure = 1 // a flow failed or the config is invalid
+	exitUsage   = 2
 )
 
 func main() {
-	configReader := config.NewBlackboxConfigReader()
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
 
-	c, _ := os.Getwd()
-	log.Infof("CWD: %v", c)
+	switch command {
+	case "serve":
+		if len(args) > 0 {
+			if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+				fmt.Println(usage)
+				os.Exit(exitSuccess)
+			}
+			fmt.Fprintf(os.Stderr, "serve takes no arguments\n\n%s\n", usage)
+			os.Exit(exitUsage)
+		}
+		serve()
+	case "run":
+		os.Exit(runCommand(args))
+	case "validate":
+		os.Exit(validateCommand(args))
+	case "list":
+		os.Exit(listCommand(args))
+	case "help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n\n%s\n", command, usage)
+		os.Exit(exitUsage)
+	}
+}
 
-	configService, err := service.CreateConfigurationService(configReader)
-	if err != nil {
-		log.Panic(err)
+// newFlagSet creates the command flags, the command log level defaults to logLevel
+func newFlagSet(command string, args string, description string, logLevel log.Level) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: service %s [flags] %s\n\n%s\n\nFlags:\n", command, args, description)
+		flags.PrintDefaults()
 	}
 
-	ctx := context.Background()
+	return flags, flags.String("log-level", logLevel.String(), "log level, logs are written to stderr")
+}
 
-	serviceFactory, err := service.NewServiceFactory(configService)
+// parseFlags parses the command flags and sets the log level
+func parseFlags(flags *flag.FlagSet, logLevel *string, args []string) error {
+	err := flags.Parse(args)
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
 
-	testManager := tester.NewManager(
-		ctx,
-		steps.NewStepFactory(),
-		serviceFactory.ConfigurationService.TesterSettings,
-		serviceFactory.MetricsService,
-	)
-
-	err = testManager.Init(serviceFactory.ConfigurationService.TesterConfig)
+	level, err := log.ParseLevel(*logLevel)
 	if err != nil {
-		log.WithError(err).Panic("failed initiating test manager")
+		fmt.Fprintln(flags.Output(), err)
+		return err
 	}
+	log.SetLevel(level)
 
-	FgBlackbox := server.NewBlackboxServer(configService.ServerSettings)
+	return nil
+}
 
-	if configService.MetricsSettings.Enabled {
-		pe, err := prometheus.NewExporter(prometheus.Options{
-			Namespace: configService.MetricsSettings.MetricPrefix,
-		})
-		if err != nil {
-			log.Panicf("Failed to create the Prometheus stats exporter: %v", err)
-		}
+// usageExitCode returns the exit code of a flags parsing error, asking for help is not an error
+func usageExitCode(err error) int {
+	if err == flag.ErrHelp {
+		return exitSuccess
+	}
 
-		go func() {
-			mux := http.NewServeMux()
-			mux.Handle(config.MetricsEndpoint, pe)
-			err := http.ListenAndServe(fmt.Sprintf(":%s", configService.MetricsSettings.MetricPort), mux)
-			if err != nil {
-				log.Panicf("Failed to start the Prometheus stats exporter: %v", err)
-			}
-		}()
+	return exitUsage
+}
+
+// loadServiceFactory loads the settings and the config file
+func loadServiceFactory(configReader config.BlackboxConfigReader) (*service.Factory, error) {
+	configService, err := service.CreateConfigurationService(configReader)
+	if err != nil {
+		return nil, err
 	}
 
-	go func() {
-		err := FgBlackbox.Start()
-		if err != http.ErrServerClosed {
-			log.WithField("error", err).Error("Failed to start fg-blackbox.")
-		}
-	}()
+	return service.NewServiceFactory(configService)
+}
 
-	// start test manager
-	testManager.Start()
-	defer testManager.Stop()
+func newManager(
+	ctx context.Context,
+	serviceFactory *service.Factory,
+	notifierFactory notifier.NotifierFactoryInterface,
+	runHistory tester.RunHistoryInterface,
+	reporter tester.RunReporterInterface,
+) tester.ManagerInterface {
+	return tester.NewManager(
+		ctx,
+		steps.NewStepFactory(),
+		notifierFactory,
+		runHistory,
+		reporter,
+		serviceFactory.ConfigurationService.TesterSettings,
+		serviceFactory.MetricsService,
+	)
+}
+
+// newReporter returns the reporter of the reports, nil when there are no reports
+func newReporter(reports []string) (tester.RunReporterInterface, error) {
+	if len(reports) == 0 {
+		return nil, nil
+	}
 
-	FgBlackbox.WaitForShutdown()
+	return report.NewReporter(report.NewWriterFactory(), reports)
 }
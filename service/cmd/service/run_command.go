@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	log "github.com/sirupsen/logrus"
+)
+
+// stringsFlag is a repeatable flag
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// runCommand runs the flows once without the scheduler and the server and prints their summary
+func runCommand(args []string) int {
+	flags, logLevel := newFlagSet("run", "[flow...]",
+		"Runs the flows once, all the flows when none is given, and exits non-zero when a flow fails", log.InfoLevel)
+	serial := flags.Bool("serial", false, "run the flows one after the other instead of concurrently")
+	notify := flags.Bool("notify", false, "send the flows notifications, they are only logged by default")
+	var reports stringsFlag
+	flags.Var(&reports, "report", "type:path report of all the runs, repeatable, defaults to TESTER_REPORTS")
+	if err := parseFlags(flags, logLevel, args); err != nil {
+		return usageExitCode(err)
+	}
+
+	serviceFactory, err := loadServiceFactory(config.NewBlackboxConfigReader())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	if len(reports) == 0 {
+		reports = serviceFactory.ConfigurationService.TesterSettings.Reports
+	}
+
+	// the reports are written once with all the runs instead of after every run
+	reporter, err := newReporter(reports)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	// interrupted runs are stopped and reported as failed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			log.Warn("interrupted, stopping the runs")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// one-shot runs from a laptop or CI must not page the on-call, notifications are opt-in
+	notifierFactory := notifier.NewNotifierFactory()
+	if !*notify {
+		notifierFactory = notifier.NewDryRunNotifierFactory(notifierFactory)
+	}
+
+	// the run history is left to the service, its database is locked while the service runs
+	testManager := newManager(ctx, serviceFactory, notifierFactory, nil, nil)
+	err = testManager.Init(serviceFactory.ConfigurationService.TesterConfig)
+	if err != nil {
+		testManager.Stop()
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	results, err := testManager.RunFlows(flags.Args(), *serial)
+	testManager.Stop()
+	if results == nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	// runs which failed to start have no result
+	finished := make([]*tester.RunResult, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			finished = append(finished, result)
+		}
+	}
+
+	printSummary(os.Stdout, finished)
+
+	if reporter != nil {
+		if err := reporter.Report(finished); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	}
+
+	if err != nil || failedRuns(finished) > 0 {
+		return exitFailure
+	}
+
+	return exitSuccess
+}
+
+func printSummary(out io.Writer, results []*tester.RunResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FLOW\tSTATUS\tDURATION\tERROR")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Flow, result.Status, formatDuration(result.DurationMS), result.Error)
+
+		for _, group := range []struct {
+			phase string
+			steps []*tester.StepResult
+		}{
+			{"setup", result.Setup},
+			{"step", result.Steps},
+			{"teardown", result.Teardown},
+		} {
+			for _, step := range group.steps {
+				fmt.Fprintf(w, "  %s %s\t%s\t%s\t%s\n", group.phase, step.Name, step.Status, formatDuration(step.DurationMS), step.Error) //nolint // line length
+			}
+		}
+	}
+	_ = w.Flush()
+
+	failed := failedRuns(results)
+	fmt.Fprintf(out, "\n%d flows, %d passed, %d failed\n", len(results), len(results)-failed, failed)
+}
+
+func failedRuns(results []*tester.RunResult) int {
+	failed := 0
+	for _, result := range results {
+		if !result.Passed() {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func formatDuration(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Millisecond).String()
+}
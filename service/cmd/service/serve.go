@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"contrib.go.opencensus.io/exporter/prometheus"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/history"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
+	"github.com/orensho/thin-slack-blackbox-tester/service/server"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	log "github.com/sirupsen/logrus"
+
+	"net/http"
+	"os"
+)
+
+// serve runs the flows on their schedule and serves the API until the server is shut down
+func serve() {
+	configReader := config.NewBlackboxConfigReader()
+
+	c, _ := os.Getwd()
+	log.Infof("CWD: %v", c)
+
+	serviceFactory, err := loadServiceFactory(configReader)
+	if err != nil {
+		log.Panic(err)
+	}
+	configService := serviceFactory.ConfigurationService
+
+	ctx := context.Background()
+
+	var runHistory history.StoreInterface
+	if serviceFactory.ConfigurationService.TesterSettings.HistoryPath != "" {
+		runHistory, err = history.NewStore(serviceFactory.ConfigurationService.TesterSettings)
+		if err != nil {
+			log.WithError(err).Panic("failed opening run history")
+		}
+		runHistory.Start()
+		defer func() {
+			if err := runHistory.Close(); err != nil {
+				log.WithError(err).Error("failed closing run history")
+			}
+		}()
+	}
+
+	reporter, err := newReporter(serviceFactory.ConfigurationService.TesterSettings.Reports)
+	if err != nil {
+		log.WithError(err).Panic("failed creating reporter")
+	}
+
+	testManager := newManager(ctx, serviceFactory, notifier.NewNotifierFactory(), runHistory, reporter)
+
+	err = testManager.Init(serviceFactory.ConfigurationService.TesterConfig)
+	if err != nil {
+		log.WithError(err).Panic("failed initiating test manager")
+	}
+
+	configReloader := tester.NewConfigReloader(
+		configReader,
+		serviceFactory.ConfigurationService.TesterSettings,
+		testManager,
+		serviceFactory.MetricsService,
+	)
+
+	FgBlackbox := server.NewBlackboxServer(
+		configService.ServerSettings,
+		configService.TesterSettings,
+		testManager,
+		configReloader,
+		runHistory,
+	)
+
+	if configService.MetricsSettings.Enabled {
+		pe, err := prometheus.NewExporter(prometheus.Options{
+			Namespace: configService.MetricsSettings.MetricPrefix,
+		})
+		if err != nil {
+			log.Panicf("Failed to create the Prometheus stats exporter: %v", err)
+		}
+
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle(config.MetricsEndpoint, pe)
+			err := http.ListenAndServe(fmt.Sprintf(":%s", configService.MetricsSettings.MetricPort), mux)
+			if err != nil {
+				log.Panicf("Failed to start the Prometheus stats exporter: %v", err)
+			}
+		}()
+	}
+
+	go func() {
+		err := FgBlackbox.Start()
+		if err != http.ErrServerClosed {
+			log.WithField("error", err).Error("Failed to start fg-blackbox.")
+		}
+	}()
+
+	// start test manager
+	testManager.Start()
+	defer testManager.Stop()
+
+	// start reloading the config on changes
+	err = configReloader.Start()
+	if err != nil {
+		log.WithError(err).Panic("failed starting config reloader")
+	}
+	defer configReloader.Stop()
+
+	FgBlackbox.WaitForShutdown()
+}
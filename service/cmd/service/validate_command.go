@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
+	"github.com/orensho/thin-slack-blackbox-tester/service/steps"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	log "github.com/sirupsen/logrus"
+)
+
+// validateCommand validates the settings and the whole config without running the flows
+func validateCommand(args []string) int {
+	flags, logLevel := newFlagSet("validate", "",
+		"Validates the settings and the config file flows, steps and notifiers", log.WarnLevel)
+	if err := parseFlags(flags, logLevel, args); err != nil {
+		return usageExitCode(err)
+	}
+
+	serviceFactory, err := loadServiceFactory(config.NewBlackboxConfigReader())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	settings := serviceFactory.ConfigurationService.TesterSettings
+	if _, err := newReporter(settings.Reports); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	testerConfig := serviceFactory.ConfigurationService.TesterConfig
+	findings := tester.ValidateConfig(testerConfig, steps.NewStepFactory(), notifier.NewNotifierFactory())
+	if len(findings) > 0 {
+		fmt.Fprintln(os.Stderr, findings.Error())
+	}
+	if findings.Err() != nil {
+		errorsCount := len(findings) - len(findings.Warnings())
+		fmt.Fprintf(os.Stderr, "%s is invalid: %d errors\n", settings.ConfigFilePath(), errorsCount)
+		return exitFailure
+	}
+
+	fmt.Printf("%s is valid: %d flows, %d steps, %d notifiers\n",
+		settings.ConfigFilePath(), len(testerConfig.Flows), len(testerConfig.Definitions), len(testerConfig.Notifiers))
+
+	return exitSuccess
+}
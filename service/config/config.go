@@ -9,7 +9,6 @@ import (
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"os"
-	"path"
 )
 
 type BlackboxConfigReader struct {
@@ -44,7 +43,7 @@ func (c *BlackboxConfigReader) LoadMetricsSettings() (*MetricsSettings, error) {
 
 func (c *BlackboxConfigReader) LoadTesterConfig(testerSettings *TesterSettings) (*TesterConfig, error) {
 	testerConfig := TesterConfig{}
-	configFilePath := path.Join(testerSettings.ConfigFolder, testerSettings.Environment, testerSettings.ConfigFilename)
+	configFilePath := testerSettings.ConfigFilePath()
 	yamlFile, err := ioutil.ReadFile(configFilePath)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed reading file: %s", configFilePath)
@@ -55,11 +54,22 @@ func (c *BlackboxConfigReader) LoadTesterConfig(testerSettings *TesterSettings)
 		return nil, errors.Wrapf(err, "failed while expanding environment variables in file: %s", configFilePath)
 	}
 
-	err = yaml.Unmarshal([]byte(yamlFileWithEnv), &testerConfig)
+	// the node tree is kept to report the position of config errors
+	var root yaml.Node
+	err = yaml.Unmarshal([]byte(yamlFileWithEnv), &root)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed unmarshalling file: %s", configFilePath)
 	}
 
+	// an empty file has no document
+	if root.Kind != 0 {
+		err = root.Decode(&testerConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed unmarshalling file: %s", configFilePath)
+		}
+	}
+	testerConfig.Source = &Source{File: configFilePath, Root: &root}
+
 	return &testerConfig, err
 }
 
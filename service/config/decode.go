@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// UnknownKeysError lists the configuration keys which are not fields of the configuration struct,
+// nested keys are dotted and list items indexed, e.g. "expect.status" or "headers[0].name"
+type UnknownKeysError struct {
+	Keys []string
+}
+
+func (e *UnknownKeysError) Error() string {
+	return "unknown keys: " + strings.Join(e.Keys, ", ")
+}
+
+// Decode decodes a step or notifier configuration into its configuration struct,
+// unlike mapstructure.Decode keys which match no field are an error
+func Decode(input map[string]interface{}, conf interface{}) error {
+	var metadata mapstructure.Metadata
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Metadata: &metadata,
+		Result:   conf,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = decoder.Decode(input)
+	if err != nil {
+		return err
+	}
+
+	if len(metadata.Unused) > 0 {
+		keys := make([]string, 0, len(metadata.Unused))
+		for _, key := range metadata.Unused {
+			keys = append(keys, inputKeyPath(input, key))
+		}
+		sort.Strings(keys)
+
+		return &UnknownKeysError{Keys: keys}
+	}
+
+	return nil
+}
+
+// inputKeyPath returns the key path as written in the input, mapstructure names the
+// parents of nested keys by their struct field names
+func inputKeyPath(input map[string]interface{}, keyPath string) string {
+	var parts []string
+	var value interface{} = input
+	for _, segment := range ParseKeyPath(keyPath) {
+		switch segment := segment.(type) {
+		case int:
+			if len(parts) == 0 {
+				parts = append(parts, "")
+			}
+			parts[len(parts)-1] += fmt.Sprintf("[%d]", segment)
+			if items, ok := value.([]interface{}); ok && segment < len(items) {
+				value = items[segment]
+			}
+		case string:
+			key := segment
+			if values, ok := value.(map[string]interface{}); ok {
+				if _, exact := values[key]; !exact {
+					for inputKey := range values {
+						if strings.EqualFold(inputKey, segment) {
+							key = inputKey
+							break
+						}
+					}
+				}
+				value = values[key]
+			}
+			parts = append(parts, key)
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
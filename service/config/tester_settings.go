@@ -1,15 +1,94 @@
 package config
 
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 type TesterSettings struct {
-	ConfigFilename   string `env:"TESTER_CONFIG_FILENAME" envDefault:"config.yaml"`
-	ConfigFolder     string `env:"TESTER_CONFIG_FOLDER" envDefault:"configuration"`
-	Environment      string `env:"TESTER_ENVIRONMENT" envDefault:"dev"`
+	ConfigFilename string `env:"TESTER_CONFIG_FILENAME" envDefault:"config.yaml"`
+	ConfigFolder   string `env:"TESTER_CONFIG_FOLDER" envDefault:"configuration"`
+	Environment    string `env:"TESTER_ENVIRONMENT" envDefault:"dev"`
+	WatchConfig    bool   `env:"TESTER_CONFIG_WATCH" envDefault:"true"`
+
+	// ArtifactsFolder is the failed runs artifacts folder, the artifacts are disabled when empty
+	ArtifactsFolder    string `env:"TESTER_ARTIFACTS_FOLDER"`
+	ArtifactsURL       string `env:"TESTER_ARTIFACTS_URL"`
+	ArtifactsRetention string `env:"TESTER_ARTIFACTS_RETENTION" envDefault:"168h"`
+
+	BrowserPoolSize            int    `env:"TESTER_BROWSER_POOL_SIZE" envDefault:"1"`
+	BrowserMaxTabs             int    `env:"TESTER_BROWSER_MAX_TABS" envDefault:"5"`
+	BrowserRecycleRuns         int    `env:"TESTER_BROWSER_RECYCLE_RUNS" envDefault:"100"`
+	BrowserHealthCheckInterval string `env:"TESTER_BROWSER_HEALTH_CHECK_INTERVAL" envDefault:"30s"`
+	BrowserRemoteURL           string `env:"TESTER_BROWSER_REMOTE_URL"`
+	ShowDebugBrowser           bool   `env:"TESTER_SHOW_DEBUG_BROWSER" envDefault:"false"`
+
+	// HistoryPath is the run history file, the run history is disabled when empty
+	HistoryPath      string `env:"TESTER_HISTORY_PATH"`
+	HistoryRetention string `env:"TESTER_HISTORY_RETENTION" envDefault:"720h"`
+
+	// Reports are the "type:path" reports written after every run, e.g. "junit:reports/{flow}.xml"
+	Reports []string `env:"TESTER_REPORTS" envSeparator:","`
+
+	ParsedBrowserHealthCheckInterval time.Duration
+	ParsedHistoryRetention           time.Duration
+	ParsedArtifactsRetention         time.Duration
+}
+
+// ConfigFilePath returns the path of the tester config file
+func (s *TesterSettings) ConfigFilePath() string {
+	return path.Join(s.ConfigFolder, s.Environment, s.ConfigFilename)
 }
 
 func (s *TesterSettings) Evaluate() error {
+	interval, err := time.ParseDuration(s.BrowserHealthCheckInterval)
+	if err != nil {
+		return errors.Wrap(err, "Unable to parse TESTER_BROWSER_HEALTH_CHECK_INTERVAL")
+	}
+	if interval <= 0 {
+		return errors.Errorf("invalid browser health check interval %s", s.BrowserHealthCheckInterval)
+	}
+	s.ParsedBrowserHealthCheckInterval = interval
+
+	retention, err := time.ParseDuration(s.HistoryRetention)
+	if err != nil {
+		return errors.Wrap(err, "Unable to parse TESTER_HISTORY_RETENTION")
+	}
+	if retention < 0 {
+		return errors.Errorf("invalid history retention %s", s.HistoryRetention)
+	}
+	s.ParsedHistoryRetention = retention
+
+	artifactsRetention, err := time.ParseDuration(s.ArtifactsRetention)
+	if err != nil {
+		return errors.Wrap(err, "Unable to parse TESTER_ARTIFACTS_RETENTION")
+	}
+	if artifactsRetention < 0 {
+		return errors.Errorf("invalid artifacts retention %s", s.ArtifactsRetention)
+	}
+	s.ParsedArtifactsRetention = artifactsRetention
+
 	return nil
 }
 
 func (s *TesterSettings) Validate() error {
+	if s.BrowserPoolSize < 1 {
+		return errors.Errorf("invalid browser pool size %d", s.BrowserPoolSize)
+	}
+
+	if s.BrowserMaxTabs < 1 {
+		return errors.Errorf("invalid browser max tabs %d", s.BrowserMaxTabs)
+	}
+
+	for _, report := range s.Reports {
+		parts := strings.SplitN(report, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return errors.Errorf("invalid report '%s', expected type:path", report)
+		}
+	}
+
 	return nil
 }
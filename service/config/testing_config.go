@@ -1,8 +1,15 @@
 package config
 
+import (
+	"gopkg.in/yaml.v3"
+)
+
 type TesterConfig struct {
 	Definitions map[string]Definition `yaml:"definitions"`
 	Flows       map[string]Flow       `yaml:"flows"`
+	Notifiers   map[string]Definition `yaml:"notifiers,omitempty"`
+
+	Source *Source `yaml:"-"` // nil when the config is not read from a file
 }
 
 type Definition struct {
@@ -11,11 +18,100 @@ type Definition struct {
 }
 
 type Flow struct {
-	Config FlowConfig `yaml:"config"`
-	Steps  []string   `yaml:"steps"`
+	Config   FlowConfig `yaml:"config"`
+	Setup    []FlowStep `yaml:"setup,omitempty"`
+	Steps    []FlowStep `yaml:"steps"`
+	Teardown []FlowStep `yaml:"teardown,omitempty"` // always runs, even after a failure or a timeout
+}
+
+// FlowStep is a step of a flow, either the step definition name
+// or a mapping of the step definition name and its run policy
+type FlowStep struct {
+	Step            string  `yaml:"step"`
+	Timeout         *string `yaml:"timeout,omitempty"`
+	Retries         int     `yaml:"retries,omitempty"`
+	Backoff         *string `yaml:"backoff,omitempty"` // delay before the first retry, doubled on every retry, default 1s
+	ContinueOnError bool    `yaml:"continueOnError,omitempty"`
+}
+
+func (s *FlowStep) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&s.Step)
+	}
+
+	type plain FlowStep
+	return value.Decode((*plain)(s))
+}
+
+// StepNames returns the names of the flow steps by order
+func StepNames(flowSteps []FlowStep) []string {
+	names := make([]string, 0, len(flowSteps))
+	for _, step := range flowSteps {
+		names = append(names, step.Step)
+	}
+
+	return names
 }
 
 type FlowConfig struct {
-	Frequency string  `yaml:"frequency"`
-	Timeout   *string `yaml:"timeout,omitempty"`
+	Frequency   string              `yaml:"frequency"`
+	Timeout     *string             `yaml:"timeout,omitempty"`
+	Variables   map[string]string   `yaml:"variables,omitempty"`
+	Maintenance []MaintenanceWindow `yaml:"maintenance,omitempty"`
+
+	TeardownTimeout *string `yaml:"teardownTimeout,omitempty"` // time budget of the teardown steps, default 1m
+
+	Notifications *NotificationsConfig `yaml:"notifications,omitempty"`
+	Browser       *BrowserConfig       `yaml:"browser,omitempty"`
+}
+
+// BrowserConfig sets the browser the flow runs in and the device it emulates
+type BrowserConfig struct {
+	// Remote is the devtools address of a running browser (ws:// URL or host:port),
+	// overrides TESTER_BROWSER_REMOTE_URL
+	Remote string `yaml:"remote,omitempty"`
+
+	Device         string             `yaml:"device,omitempty"` // device preset, e.g. "iPhone X" or "iPad Mini landscape"
+	Viewport       *ViewportConfig    `yaml:"viewport,omitempty"`
+	UserAgent      string             `yaml:"userAgent,omitempty"`
+	Locale         string             `yaml:"locale,omitempty"`
+	AcceptLanguage string             `yaml:"acceptLanguage,omitempty"` // defaults to the locale
+	Timezone       string             `yaml:"timezone,omitempty"`
+	Geolocation    *GeolocationConfig `yaml:"geolocation,omitempty"`
+	ColorScheme    string             `yaml:"colorScheme,omitempty"` // light, dark or no-preference
+
+	// Flags are extra Chrome command line flags, flows with flags get their own browsers
+	Flags map[string]interface{} `yaml:"flags,omitempty"`
+}
+
+// ViewportConfig overrides the viewport of the device preset
+type ViewportConfig struct {
+	Width             int64   `yaml:"width"`
+	Height            int64   `yaml:"height"`
+	DeviceScaleFactor float64 `yaml:"deviceScaleFactor,omitempty"`
+	Mobile            bool    `yaml:"mobile,omitempty"`
+	Touch             bool    `yaml:"touch,omitempty"`
+}
+
+type GeolocationConfig struct {
+	Latitude  float64 `yaml:"latitude"`
+	Longitude float64 `yaml:"longitude"`
+	Accuracy  float64 `yaml:"accuracy,omitempty"` // meters, default 1
+}
+
+// NotificationsConfig sets which notifiers are notified when the flow fails and recovers
+type NotificationsConfig struct {
+	Notifiers        []string `yaml:"notifiers"`
+	FailureThreshold int      `yaml:"failureThreshold,omitempty"` // consecutive failures before notifying, default 1
+	Recovery         *bool    `yaml:"recovery,omitempty"`         // notify when the flow recovers, default true
+	RepeatInterval   string   `yaml:"repeatInterval,omitempty"`   // notify again while failing, default never
+}
+
+// MaintenanceWindow is either recurring, a cron Schedule of the window start and a Duration,
+// or absolute, RFC3339 Start and End times
+type MaintenanceWindow struct {
+	Schedule string `yaml:"schedule,omitempty"`
+	Duration string `yaml:"duration,omitempty"`
+	Start    string `yaml:"start,omitempty"`
+	End      string `yaml:"end,omitempty"`
 }
@@ -0,0 +1,319 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlMergeKey is the yaml merge key, merged mappings are checked where they are defined
+const yamlMergeKey = "<<"
+
+// Source is the parsed config file, used to report the position of config errors
+type Source struct {
+	File string
+	Root *yaml.Node
+}
+
+// ValidationError is a config error at its position in the config file
+type ValidationError struct {
+	File    string
+	Line    int // 0 when the position is unknown
+	Column  int
+	Message string
+	Warning bool // warnings do not fail the validation, e.g. unused definitions
+}
+
+func (e *ValidationError) Error() string {
+	message := e.Message
+	if e.Warning {
+		message = "warning: " + message
+	}
+
+	switch {
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, message)
+	default:
+		return message
+	}
+}
+
+// ValidationErrors are all the config errors and warnings
+type ValidationErrors []*ValidationError
+
+// Error lists the errors sorted by position, one per line
+func (e ValidationErrors) Error() string {
+	sorted := make(ValidationErrors, len(e))
+	copy(sorted, e)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Line != sorted[j].Line {
+			return sorted[i].Line < sorted[j].Line
+		}
+		return sorted[i].Column < sorted[j].Column
+	})
+
+	lines := make([]string, 0, len(sorted))
+	for _, err := range sorted {
+		lines = append(lines, err.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Err returns the errors without the warnings, nil when there are only warnings
+func (e ValidationErrors) Err() error {
+	var errs ValidationErrors
+	for _, err := range e {
+		if !err.Warning {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// Warnings returns the warnings
+func (e ValidationErrors) Warnings() ValidationErrors {
+	var warnings ValidationErrors
+	for _, err := range e {
+		if err.Warning {
+			warnings = append(warnings, err)
+		}
+	}
+
+	return warnings
+}
+
+// Errorf returns an error positioned at the config path, a path of mapping keys and sequence
+// indexes, e.g. "flows", "login", "steps", 2. When the path is partly missing the error is
+// positioned at its deepest existing node
+func (c *TesterConfig) Errorf(path []interface{}, format string, args ...interface{}) *ValidationError {
+	err := &ValidationError{Message: fmt.Sprintf(format, args...)}
+	if c.Source == nil {
+		return err
+	}
+
+	err.File = c.Source.File
+	if node := c.Source.find(path); node != nil {
+		err.Line = node.Line
+		err.Column = node.Column
+	}
+
+	return err
+}
+
+// Warnf returns a warning positioned at the config path
+func (c *TesterConfig) Warnf(path []interface{}, format string, args ...interface{}) *ValidationError {
+	err := c.Errorf(path, format, args...)
+	err.Warning = true
+
+	return err
+}
+
+// find returns the node of the path, the key node when the path ends with a mapping key
+func (s *Source) find(path []interface{}) *yaml.Node {
+	if s.Root == nil {
+		return nil
+	}
+
+	node := s.Root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	found := node
+	for _, segment := range path {
+		node = resolveAlias(node)
+
+		switch segment := segment.(type) {
+		case string:
+			key, value := mappingEntry(node, segment)
+			if key == nil {
+				return found
+			}
+			found, node = key, value
+		case int:
+			if node.Kind != yaml.SequenceNode || segment < 0 || segment >= len(node.Content) {
+				return found
+			}
+			node = node.Content[segment]
+			found = node
+		}
+	}
+
+	return found
+}
+
+// mappingEntry returns the key and value nodes of the mapping key, keys are matched case
+// insensitively when there is no exact match as step and notifier configurations are
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var foldKey, foldValue *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+		if foldKey == nil && strings.EqualFold(node.Content[i].Value, key) {
+			foldKey, foldValue = node.Content[i], node.Content[i+1]
+		}
+	}
+
+	return foldKey, foldValue
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
+
+// ParseKeyPath splits a dotted and indexed key path, as reported by UnknownKeysError, into
+// config path segments, e.g. "headers[0].name" into "headers", 0, "name"
+func ParseKeyPath(keyPath string) []interface{} {
+	var path []interface{}
+	for _, part := range strings.Split(keyPath, ".") {
+		for {
+			open := strings.Index(part, "[")
+			if open < 0 {
+				if part != "" {
+					path = append(path, part)
+				}
+				break
+			}
+
+			if open > 0 {
+				path = append(path, part[:open])
+			}
+
+			end := strings.Index(part[open:], "]")
+			if end < 0 {
+				path = append(path, part[open:])
+				break
+			}
+
+			index := part[open+1 : open+end]
+			if i, err := strconv.Atoi(index); err == nil {
+				path = append(path, i)
+			} else {
+				path = append(path, index) // map keys are indexed too
+			}
+			part = part[open+end+1:]
+		}
+	}
+
+	return path
+}
+
+// ValidateKeys returns an error for every config key which is not a known field,
+// step and notifier configurations are checked by their types
+func (c *TesterConfig) ValidateKeys() ValidationErrors {
+	if c.Source == nil || c.Source.Root == nil {
+		return nil
+	}
+
+	root := c.Source.Root
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	var errs ValidationErrors
+	c.Source.checkKeys(root, reflect.TypeOf(TesterConfig{}), &errs)
+
+	return errs
+}
+
+func (s *Source) checkKeys(node *yaml.Node, t reflect.Type, errs *ValidationErrors) {
+	node = resolveAlias(node)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return // scalar forms and type errors are handled when decoding
+		}
+
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == yamlMergeKey {
+				continue
+			}
+
+			field, ok := fields[key.Value]
+			if !ok {
+				*errs = append(*errs, &ValidationError{
+					File:    s.File,
+					Line:    key.Line,
+					Column:  key.Column,
+					Message: fmt.Sprintf("unknown key '%s', expected one of: %s", key.Value, strings.Join(sortedKeys(fields), ", ")), //nolint // line length
+				})
+				continue
+			}
+
+			s.checkKeys(value, field, errs)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		for i := 1; i < len(node.Content); i += 2 {
+			s.checkKeys(node.Content[i], t.Elem(), errs)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		for _, item := range node.Content {
+			s.checkKeys(item, t.Elem(), errs)
+		}
+	}
+}
+
+// yamlFields returns the struct fields types by their yaml key
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("yaml")
+		if tag == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+func sortedKeys(fields map[string]reflect.Type) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
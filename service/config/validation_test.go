@@ -0,0 +1,181 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseKeyPath(t *testing.T) {
+	tests := []struct {
+		keyPath string
+		want    []interface{}
+	}{
+		{"url", []interface{}{"url"}},
+		{"expect.status", []interface{}{"expect", "status"}},
+		{"headers[0].name", []interface{}{"headers", 0, "name"}},
+		{"matrix[1][2]", []interface{}{"matrix", 1, 2}},
+		{"headers[X-Token]", []interface{}{"headers", "X-Token"}},
+		{"items[0", []interface{}{"items", "[0"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyPath, func(t *testing.T) {
+			if got := ParseKeyPath(tt.keyPath); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %#v got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+const testValidationConfig = `definitions:
+  base: &base
+    type: http-step
+    config:
+      url: https://example.com
+  login:
+    <<: *base
+    config:
+      URL: https://example.com/login
+flows:
+  main:
+    config: &flowConfig
+      frequency: '@every 1m'
+    steps:
+      - login
+      - step: base
+        retries: 2
+  copy:
+    config: *flowConfig
+    steps: [base]
+`
+
+func newTestTesterConfig(t *testing.T, text string) *TesterConfig {
+	t.Helper()
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		t.Fatalf("failed parsing config: %v", err)
+	}
+
+	conf := &TesterConfig{}
+	if err := root.Decode(conf); err != nil {
+		t.Fatalf("failed decoding config: %v", err)
+	}
+	conf.Source = &Source{File: "config.yaml", Root: &root}
+
+	return conf
+}
+
+func TestErrorfPosition(t *testing.T) {
+	conf := newTestTesterConfig(t, testValidationConfig)
+
+	tests := []struct {
+		name string
+		path []interface{}
+		want string
+	}{
+		{"mapping key", []interface{}{"flows", "main"}, "config.yaml:11:3: error"},
+		{"nested key", []interface{}{"definitions", "base", "config", "url"}, "config.yaml:5:7: error"},
+		{"sequence item", []interface{}{"flows", "main", "steps", 1}, "config.yaml:16:9: error"},
+		{"key in sequence item", []interface{}{"flows", "main", "steps", 1, "retries"}, "config.yaml:17:9: error"},
+		{"case insensitive key", []interface{}{"definitions", "login", "config", "url"}, "config.yaml:9:7: error"},
+		{"through an alias", []interface{}{"flows", "copy", "config", "frequency"}, "config.yaml:13:7: error"},
+		{"merged key at the merging mapping", []interface{}{"definitions", "login", "type"}, "config.yaml:6:3: error"},
+		{"missing key at the deepest node", []interface{}{"flows", "main", "config", "timeout"}, "config.yaml:12:5: error"},
+		{"index out of range", []interface{}{"flows", "main", "steps", 5}, "config.yaml:14:5: error"},
+		{"root", nil, "config.yaml:1:1: error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conf.Errorf(tt.path, "error").Error(); got != tt.want {
+				t.Fatalf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+
+	warning := conf.Warnf([]interface{}{"flows"}, "unused").Error()
+	if warning != "config.yaml:10:1: warning: unused" {
+		t.Fatalf("unexpected warning %q", warning)
+	}
+
+	if got := (&TesterConfig{}).Errorf([]interface{}{"flows"}, "no source").Error(); got != "no source" {
+		t.Fatalf("expected the message only got %q", got)
+	}
+}
+
+func TestValidateKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"valid", testValidationConfig, nil},
+		{
+			name: "unknown top level key",
+			text: "definitions: {}\nflow: {}\n",
+			want: []string{"config.yaml:2:1: unknown key 'flow', expected one of: definitions, flows, notifiers"},
+		},
+		{
+			name: "unknown flow keys",
+			text: "flows:\n  main:\n    config:\n      frequncy: '@every 1m'\n    steps:\n      - step: a\n        retry: 1\n",
+			want: []string{
+				"config.yaml:4:7: unknown key 'frequncy'",
+				"config.yaml:7:9: unknown key 'retry'",
+			},
+		},
+		{
+			name: "step configs are not checked",
+			text: "definitions:\n  a:\n    type: http-step\n    config:\n      anything: 1\n",
+		},
+		{
+			name: "unknown key in a merged anchor is reported once where it is defined",
+			text: "flows:\n  a:\n    config: &shared\n      timout: 1m\n  b:\n    config:\n      <<: *shared\n      frequency: '@every 1m'\n", //nolint // line length
+			want: []string{"config.yaml:4:7: unknown key 'timout'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := newTestTesterConfig(t, tt.text).ValidateKeys()
+			if len(errs) != len(tt.want) {
+				t.Fatalf("expected %d errors got %d: %v", len(tt.want), len(errs), errs)
+			}
+
+			for i, err := range errs {
+				if !strings.HasPrefix(err.Error(), tt.want[i]) {
+					t.Errorf("expected %q got %q", tt.want[i], err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	errs := ValidationErrors{
+		{File: "config.yaml", Line: 9, Column: 3, Message: "second"},
+		{File: "config.yaml", Line: 2, Column: 1, Message: "unused", Warning: true},
+		{File: "config.yaml", Message: "first"},
+	}
+
+	want := "config.yaml: first\nconfig.yaml:2:1: warning: unused\nconfig.yaml:9:3: second"
+	if got := errs.Error(); got != want {
+		t.Fatalf("expected %q got %q", want, got)
+	}
+
+	if warnings := errs.Warnings(); len(warnings) != 1 || warnings[0].Message != "unused" {
+		t.Fatalf("expected the warning got %v", warnings)
+	}
+
+	if err := errs.Err(); err == nil || len(err.(ValidationErrors)) != 2 {
+		t.Fatalf("expected 2 errors got %v", err)
+	}
+
+	if err := errs.Warnings().Err(); err != nil {
+		t.Fatalf("expected no error for warnings only got %v", err)
+	}
+}
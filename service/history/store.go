@@ -0,0 +1,311 @@
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+
+	openTimeout   = 5 * time.Second
+	pruneInterval = time.Hour
+)
+
+var ErrRunNotFound = errors.New("run not found")
+
+var (
+	// runsBucket holds the runs JSON by run id
+	runsBucket = []byte("runs")
+	// startTimeBucket indexes the run ids by start time, keys are the big endian
+	// start time in nanoseconds followed by the run id
+	startTimeBucket = []byte("runs_by_start_time")
+)
+
+// Query filters the runs, empty fields match all the runs
+type Query struct {
+	Flow   string
+	Status string
+	From   time.Time
+	To     time.Time
+	Limit  int // DefaultQueryLimit when 0
+}
+
+// StoreInterface persists the flow runs in an embedded database, runs older
+// than the retention are pruned
+type StoreInterface interface {
+	tester.RunHistoryInterface
+	// Get returns the run by its id
+	Get(runID string) (*tester.RunResult, error)
+	// Query returns the matching runs, latest first
+	Query(query Query) ([]*tester.RunResult, error)
+	// CountByStatus returns the number of matching runs by status, the limit is ignored
+	CountByStatus(query Query) (map[string]int, error)
+	// Start prunes the expired runs periodically
+	Start()
+	Close() error
+}
+
+type storeImpl struct {
+	db        *bolt.DB
+	retention time.Duration // 0 keeps the runs forever
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewStore(testerSettings *config.TesterSettings) (StoreInterface, error) {
+	err := os.MkdirAll(path.Dir(testerSettings.HistoryPath), 0755) //nolint:gosec // history is not secret
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating history folder")
+	}
+
+	db, err := bolt.Open(testerSettings.HistoryPath, 0644, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed opening history '%s'", testerSettings.HistoryPath)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{runsBucket, startTimeBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed creating history buckets")
+	}
+
+	return &storeImpl{
+		db:        db,
+		retention: testerSettings.ParsedHistoryRetention,
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+func (s *storeImpl) Start() {
+	s.wg.Add(1)
+	go s.pruneLoop()
+}
+
+// Close stops the pruning and closes the database
+func (s *storeImpl) Close() error {
+	close(s.stop)
+	s.wg.Wait()
+
+	return s.db.Close()
+}
+
+func (s *storeImpl) Save(result *tester.RunResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errors.Wrap(err, "failed encoding run")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(runsBucket).Put([]byte(result.RunID), data)
+		if err != nil {
+			return errors.Wrap(err, "failed saving run")
+		}
+
+		err = tx.Bucket(startTimeBucket).Put(startTimeKey(result.StartTime, result.RunID), nil)
+		if err != nil {
+			return errors.Wrap(err, "failed indexing run")
+		}
+
+		return nil
+	})
+}
+
+func (s *storeImpl) Get(runID string) (*tester.RunResult, error) {
+	var result *tester.RunResult
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		result, err = getRun(tx, []byte(runID))
+		return err
+	})
+
+	return result, err
+}
+
+func (s *storeImpl) Query(query Query) ([]*tester.RunResult, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+
+	results := []*tester.RunResult{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return walkRuns(tx, query, func(runID []byte) (bool, error) {
+			result, err := getRun(tx, runID)
+			if err != nil {
+				return false, err
+			}
+
+			if (query.Flow == "" || result.Flow == query.Flow) && (query.Status == "" || result.Status == query.Status) {
+				results = append(results, result)
+			}
+
+			return len(results) < limit, nil
+		})
+	})
+
+	return results, err
+}
+
+func (s *storeImpl) CountByStatus(query Query) (map[string]int, error) {
+	counts := map[string]int{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return walkRuns(tx, query, func(runID []byte) (bool, error) {
+			// only the fields counted are decoded
+			var result struct {
+				Flow   string `json:"flow"`
+				Status string `json:"status"`
+			}
+			data := tx.Bucket(runsBucket).Get(runID)
+			if data == nil {
+				return false, errors.Wrapf(ErrRunNotFound, "run '%s'", runID)
+			}
+			if err := json.Unmarshal(data, &result); err != nil {
+				return false, errors.Wrapf(err, "failed decoding run '%s'", runID)
+			}
+
+			if (query.Flow == "" || result.Flow == query.Flow) && (query.Status == "" || result.Status == query.Status) {
+				counts[result.Status]++
+			}
+
+			return true, nil
+		})
+	})
+
+	return counts, err
+}
+
+// walkRuns calls visit with the id of the runs started in the query time range, latest
+// first, until visit returns false
+func walkRuns(tx *bolt.Tx, query Query, visit func(runID []byte) (bool, error)) error {
+	cursor := tx.Bucket(startTimeBucket).Cursor()
+
+	// walk the index backwards from the end of the range
+	var key []byte
+	if query.To.IsZero() {
+		key, _ = cursor.Last()
+	} else if key, _ = cursor.Seek(startTimeKey(query.To.Add(time.Nanosecond), "")); key == nil {
+		key, _ = cursor.Last()
+	} else {
+		key, _ = cursor.Prev()
+	}
+
+	for ; key != nil; key, _ = cursor.Prev() {
+		startTime, runID := parseStartTimeKey(key)
+		if !query.From.IsZero() && startTime.Before(query.From) {
+			break
+		}
+
+		next, err := visit(runID)
+		if err != nil || !next {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *storeImpl) pruneLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		s.prune()
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune deletes the runs which started before the retention
+func (s *storeImpl) prune() {
+	if s.retention == 0 {
+		return
+	}
+
+	cutoff := startTimeKey(time.Now().Add(-s.retention), "")
+	pruned := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		index := tx.Bucket(startTimeBucket)
+		runs := tx.Bucket(runsBucket)
+
+		// keys are collected first, deleting while iterating skips keys
+		var expired [][]byte
+		cursor := index.Cursor()
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key, cutoff) < 0; key, _ = cursor.Next() {
+			expired = append(expired, append([]byte{}, key...))
+		}
+
+		for _, key := range expired {
+			_, runID := parseStartTimeKey(key)
+			if err := runs.Delete(runID); err != nil {
+				return err
+			}
+			if err := index.Delete(key); err != nil {
+				return err
+			}
+		}
+		pruned = len(expired)
+
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("failed pruning run history")
+		return
+	}
+
+	if pruned > 0 {
+		log.Infof("pruned %d runs older than %s from the run history", pruned, s.retention)
+	}
+}
+
+func getRun(tx *bolt.Tx, runID []byte) (*tester.RunResult, error) {
+	data := tx.Bucket(runsBucket).Get(runID)
+	if data == nil {
+		return nil, errors.Wrapf(ErrRunNotFound, "run '%s'", runID)
+	}
+
+	var result tester.RunResult
+	err := json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decoding run '%s'", runID)
+	}
+
+	return &result, nil
+}
+
+func startTimeKey(startTime time.Time, runID string) []byte {
+	key := make([]byte, 8, 8+len(runID))
+	binary.BigEndian.PutUint64(key, uint64(startTime.UnixNano()))
+
+	return append(key, runID...)
+}
+
+func parseStartTimeKey(key []byte) (time.Time, []byte) {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8]))), key[8:]
+}
@@ -0,0 +1,189 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStore(t *testing.T) StoreInterface {
+	t.Helper()
+
+	store, err := NewStore(&config.TesterSettings{HistoryPath: filepath.Join(t.TempDir(), "history.db")})
+	if err != nil {
+		t.Fatalf("failed creating store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func TestStoreCountByStatus(t *testing.T) {
+	store := newTestStore(t)
+
+	// more runs than a query returns
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []string{tester.StatusSuccess, tester.StatusSuccess, tester.StatusWarning, tester.StatusError, tester.StatusTimeout}
+	for i := 0; i < MaxQueryLimit+500; i++ {
+		for _, flow := range []string{"login", "checkout"} {
+			err := store.Save(&tester.RunResult{
+				RunID:     fmt.Sprintf("%s-%d", flow, i),
+				Flow:      flow,
+				Status:    statuses[i%len(statuses)],
+				StartTime: start.Add(time.Duration(i) * time.Minute),
+			})
+			if err != nil {
+				t.Fatalf("failed saving run: %v", err)
+			}
+		}
+	}
+
+	counts, err := store.CountByStatus(Query{Flow: "login"})
+	if err != nil {
+		t.Fatalf("failed counting runs: %v", err)
+	}
+	expected := map[string]int{
+		tester.StatusSuccess: 600,
+		tester.StatusWarning: 300,
+		tester.StatusError:   300,
+		tester.StatusTimeout: 300,
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(expected) {
+		t.Fatalf("expected counts %v got %v", expected, counts)
+	}
+
+	// the time range bounds are inclusive
+	counts, err = store.CountByStatus(Query{Flow: "login", From: start.Add(10 * time.Minute), To: start.Add(19 * time.Minute)})
+	if err != nil {
+		t.Fatalf("failed counting runs: %v", err)
+	}
+	expected = map[string]int{
+		tester.StatusSuccess: 4,
+		tester.StatusWarning: 2,
+		tester.StatusError:   2,
+		tester.StatusTimeout: 2,
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(expected) {
+		t.Fatalf("expected counts %v got %v", expected, counts)
+	}
+
+	results, err := store.Query(Query{Flow: "login", Limit: MaxQueryLimit})
+	if err != nil {
+		t.Fatalf("failed querying runs: %v", err)
+	}
+	if len(results) != MaxQueryLimit || results[0].RunID != fmt.Sprintf("login-%d", MaxQueryLimit+499) {
+		t.Fatalf("expected the latest %d runs got %d", MaxQueryLimit, len(results))
+	}
+}
+
+func TestStoreGet(t *testing.T) {
+	store := newTestStore(t)
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := &tester.RunResult{
+		RunID:     "login-1",
+		Flow:      "login",
+		Status:    tester.StatusError,
+		StartTime: start,
+		Error:     "step 'open' failed",
+		Steps:     []*tester.StepResult{{Name: "open", Status: tester.StatusError}},
+	}
+	if err := store.Save(run); err != nil {
+		t.Fatalf("failed saving run: %v", err)
+	}
+
+	result, err := store.Get("login-1")
+	if err != nil {
+		t.Fatalf("failed getting run: %v", err)
+	}
+	if result.RunID != run.RunID || result.Flow != run.Flow || result.Status != run.Status || result.Error != run.Error ||
+		!result.StartTime.Equal(start) || len(result.Steps) != 1 || result.Steps[0].Name != "open" {
+		t.Fatalf("expected the saved run got %+v", result)
+	}
+
+	if _, err := store.Get("login-2"); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected run not found got %v", err)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	now := time.Now()
+	runs := []struct {
+		runID string
+		age   time.Duration
+	}{
+		{"login-old", 72 * time.Hour},
+		{"checkout-old", 25 * time.Hour},
+		{"login-recent", 23 * time.Hour},
+		{"checkout-recent", time.Minute},
+	}
+
+	tests := []struct {
+		name      string
+		retention time.Duration
+		want      []string
+	}{
+		{"retention", 24 * time.Hour, []string{"checkout-recent", "login-recent"}},
+		{"no retention keeps the runs forever", 0, []string{"checkout-old", "checkout-recent", "login-old", "login-recent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(&config.TesterSettings{
+				HistoryPath:            filepath.Join(t.TempDir(), "history.db"),
+				ParsedHistoryRetention: tt.retention,
+			})
+			if err != nil {
+				t.Fatalf("failed creating store: %v", err)
+			}
+
+			for _, run := range runs {
+				err := store.Save(&tester.RunResult{RunID: run.runID, Status: tester.StatusSuccess, StartTime: now.Add(-run.age)})
+				if err != nil {
+					t.Fatalf("failed saving run: %v", err)
+				}
+			}
+
+			// the loop prunes when started, it only receives the stop once the first prune is done
+			store.Start()
+			impl := store.(*storeImpl)
+			impl.stop <- struct{}{}
+
+			var runIDs, indexedIDs []string
+			err = impl.db.View(func(tx *bolt.Tx) error {
+				err := tx.Bucket(runsBucket).ForEach(func(key, _ []byte) error {
+					runIDs = append(runIDs, string(key))
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				return tx.Bucket(startTimeBucket).ForEach(func(key, _ []byte) error {
+					_, runID := parseStartTimeKey(key)
+					indexedIDs = append(indexedIDs, string(runID))
+					return nil
+				})
+			})
+			if err != nil {
+				t.Fatalf("failed reading buckets: %v", err)
+			}
+
+			sort.Strings(indexedIDs)
+			if fmt.Sprint(runIDs) != fmt.Sprint(tt.want) || fmt.Sprint(indexedIDs) != fmt.Sprint(tt.want) {
+				t.Fatalf("expected runs %v got %v indexed %v", tt.want, runIDs, indexedIDs)
+			}
+
+			if err := store.Close(); err != nil {
+				t.Fatalf("failed closing store: %v", err)
+			}
+		})
+	}
+}
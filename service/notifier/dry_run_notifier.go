@@ -0,0 +1,40 @@
+package notifier
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// dryRunNotifierFactory creates the notifiers of the wrapped factory which log
+// the notifications instead of sending them, the configurations are still validated
+type dryRunNotifierFactory struct {
+	factory NotifierFactoryInterface
+}
+
+func NewDryRunNotifierFactory(factory NotifierFactoryInterface) NotifierFactoryInterface {
+	return &dryRunNotifierFactory{factory: factory}
+}
+
+func (nf *dryRunNotifierFactory) NewNotifier(notifierType string) (NotifierInterface, error) {
+	notifier, err := nf.factory.NewNotifier(notifierType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dryRunNotifier{NotifierInterface: notifier}, nil
+}
+
+type dryRunNotifier struct {
+	NotifierInterface
+}
+
+func (n *dryRunNotifier) Notify(_ context.Context, notification *Notification) error {
+	log.WithFields(log.Fields{
+		"notifier": n.GetName(),
+		"flow":     notification.Flow,
+		"runId":    notification.RunID,
+	}).Infof("notifications disabled, not sending: %s", notification.Title())
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const emailNotifierType = "email"
+
+const defaultSMTPPort = 25
+
+type emailNotifierConf struct {
+	Host     string `validate:"required"`
+	Port     int    `validate:"gte=0,lte=65535"`
+	Username string
+	Password string
+	From     string   `validate:"required,email"`
+	To       []string `validate:"required,min=1,dive,email"`
+	// InsecureSkipVerify skips the server certificate verification on STARTTLS
+	InsecureSkipVerify bool
+}
+
+// emailNotifier sends the notification by SMTP, STARTTLS is used when the server supports it
+type emailNotifier struct {
+	name string
+	conf emailNotifierConf
+}
+
+func (n *emailNotifier) GetType() string {
+	return emailNotifierType
+}
+
+func (n *emailNotifier) GetName() string {
+	return n.name
+}
+
+func (n *emailNotifier) Init(name string, input map[string]interface{}) error {
+	var conf emailNotifierConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing notifier '%s' configuration", n.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating notifier '%s' configuration", n.GetType())
+	}
+
+	if conf.Port == 0 {
+		conf.Port = defaultSMTPPort
+	}
+
+	n.name = name
+	n.conf = conf
+
+	return nil
+}
+
+func (n *emailNotifier) Notify(ctx context.Context, notification *Notification) error {
+	address := net.JoinHostPort(n.conf.Host, strconv.Itoa(n.conf.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "failed connecting to %s", address)
+	}
+
+	// net/smtp does not support contexts, the deadline covers the whole session
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.conf.Host)
+	if err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "failed creating smtp client")
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{
+			ServerName:         n.conf.Host,
+			InsecureSkipVerify: n.conf.InsecureSkipVerify, //nolint:gosec // configurable for internal relays
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed starting tls")
+		}
+	}
+
+	if n.conf.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", n.conf.Username, n.conf.Password, n.conf.Host))
+		if err != nil {
+			return errors.Wrap(err, "failed authenticating")
+		}
+	}
+
+	err = client.Mail(n.conf.From)
+	if err != nil {
+		return errors.Wrap(err, "failed setting sender")
+	}
+
+	for _, to := range n.conf.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return errors.Wrapf(err, "failed setting recipient %s", to)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "failed starting message")
+	}
+
+	_, err = writer.Write(n.message(notification))
+	if err != nil {
+		return errors.Wrap(err, "failed writing message")
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed sending message")
+	}
+
+	return client.Quit()
+}
+
+func (n *emailNotifier) message(notification *Notification) []byte {
+	headers := []string{
+		fmt.Sprintf("From: %s", n.conf.From),
+		fmt.Sprintf("To: %s", strings.Join(n.conf.To, ", ")),
+		fmt.Sprintf("Subject: %s", notification.Title()),
+		fmt.Sprintf("Date: %s", time.Now().Format(time.RFC1123Z)),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+
+	body := strings.ReplaceAll(notification.Text(), "\n", "\r\n")
+
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")
+}
@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpSession is what the fake SMTP server received
+type smtpSession struct {
+	from string
+	to   []string
+	data string
+}
+
+// newFakeSMTPServer accepts a single SMTP session without STARTTLS nor authentication
+func newFakeSMTPServer(t *testing.T) (string, int, <-chan smtpSession) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var session smtpSession
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			fmt.Fprintf(conn, "%s\r\n", line)
+		}
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+			switch command {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL":
+				session.from = line
+				reply("250 OK")
+			case "RCPT":
+				session.to = append(session.to, line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				session.data = data.String()
+				reply("250 OK")
+			case "QUIT":
+				reply("221 bye")
+				sessions <- session
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return host, portNumber, sessions
+}
+
+func TestEmailNotifier(t *testing.T) {
+	host, port, sessions := newFakeSMTPServer(t)
+
+	n := &emailNotifier{}
+	err := n.Init("email", map[string]interface{}{
+		"host": host,
+		"port": port,
+		"from": "tester@example.com",
+		"to":   []interface{}{"oncall@example.com", "team@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("failed initializing notifier: %v", err)
+	}
+
+	if err := n.Notify(context.Background(), newTestNotification(KindFailure)); err != nil {
+		t.Fatalf("failed notifying: %v", err)
+	}
+
+	session := <-sessions
+	if session.from != "MAIL FROM:<tester@example.com>" {
+		t.Errorf("expected the sender got %q", session.from)
+	}
+	if len(session.to) != 2 || session.to[0] != "RCPT TO:<oncall@example.com>" || session.to[1] != "RCPT TO:<team@example.com>" {
+		t.Errorf("expected both recipients got %q", session.to)
+	}
+
+	for _, expected := range []string{
+		"From: tester@example.com\r\n",
+		"To: oncall@example.com, team@example.com\r\n",
+		"Subject: [prod] flow checkout failed 3 times in a row\r\n",
+		"\r\n\r\nFlow: checkout\r\nRun: run-1\r\n",
+		"Error: button not found\r\n",
+	} {
+		if !strings.Contains(session.data, expected) {
+			t.Errorf("expected message to contain %q got:\n%s", expected, session.data)
+		}
+	}
+}
+
+func TestEmailNotifierUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening: %v", err)
+	}
+	address := listener.Addr().(*net.TCPAddr)
+	_ = listener.Close()
+
+	n := &emailNotifier{}
+	err = n.Init("email", map[string]interface{}{
+		"host": "127.0.0.1",
+		"port": address.Port,
+		"from": "tester@example.com",
+		"to":   []interface{}{"oncall@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("failed initializing notifier: %v", err)
+	}
+
+	if err := n.Notify(context.Background(), newTestNotification(KindFailure)); err == nil {
+		t.Fatal("expected an error when the server is unreachable")
+	}
+}
@@ -0,0 +1,60 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	KindFailure  = "failure"
+	KindRecovery = "recovery"
+)
+
+// Notification is sent when a flow starts failing and when it recovers
+type Notification struct {
+	Kind                string    `json:"kind"`
+	Flow                string    `json:"flow"`
+	RunID               string    `json:"runId"`
+	Status              string    `json:"status"`
+	Environment         string    `json:"environment"`
+	FailedStep          string    `json:"failedStep,omitempty"`
+	Error               string    `json:"error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	Artifacts           string    `json:"artifacts,omitempty"`
+	Time                time.Time `json:"time"`
+}
+
+// Title returns a one line summary of the notification
+func (n *Notification) Title() string {
+	if n.Kind == KindRecovery {
+		return fmt.Sprintf("[%s] flow %s recovered", n.Environment, n.Flow)
+	}
+
+	return fmt.Sprintf("[%s] flow %s failed %d times in a row", n.Environment, n.Flow, n.ConsecutiveFailures)
+}
+
+// Text returns the notification details, one per line
+func (n *Notification) Text() string {
+	lines := []string{
+		fmt.Sprintf("Flow: %s", n.Flow),
+		fmt.Sprintf("Run: %s", n.RunID),
+		fmt.Sprintf("Status: %s", n.Status),
+	}
+
+	if n.FailedStep != "" {
+		lines = append(lines, fmt.Sprintf("Failed step: %s", n.FailedStep))
+	}
+
+	if n.Error != "" {
+		lines = append(lines, fmt.Sprintf("Error: %s", n.Error))
+	}
+
+	if n.Artifacts != "" {
+		lines = append(lines, fmt.Sprintf("Artifacts: %s", n.Artifacts))
+	}
+
+	lines = append(lines, fmt.Sprintf("Time: %s", n.Time.Format(time.RFC3339)))
+
+	return strings.Join(lines, "\n")
+}
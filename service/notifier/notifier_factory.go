@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"github.com/pkg/errors"
+)
+
+type NotifierFactoryInterface interface {
+	NewNotifier(notifierType string) (NotifierInterface, error)
+}
+
+type notifierFactoryImpl struct{}
+
+func NewNotifierFactory() NotifierFactoryInterface {
+	return &notifierFactoryImpl{}
+}
+
+func (nf *notifierFactoryImpl) NewNotifier(notifierType string) (NotifierInterface, error) {
+	switch notifierType {
+	case webhookNotifierType:
+		return &webhookNotifier{}, nil
+	case slackNotifierType:
+		return &slackNotifier{}, nil
+	case emailNotifierType:
+		return &emailNotifier{}, nil
+	default:
+		return nil, errors.Errorf("Undefined notifier '%s'", notifierType)
+	}
+}
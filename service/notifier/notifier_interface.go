@@ -0,0 +1,12 @@
+package notifier
+
+import (
+	"context"
+)
+
+type NotifierInterface interface {
+	GetType() string
+	GetName() string
+	Init(name string, conf map[string]interface{}) error
+	Notify(ctx context.Context, notification *Notification) error
+}
@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const slackNotifierType = "slack"
+
+type slackNotifierConf struct {
+	URL       string `validate:"required,url"`
+	Channel   string
+	Username  string
+	IconEmoji string
+}
+
+type slackPayload struct {
+	Text      string `json:"text"`
+	Channel   string `json:"channel,omitempty"`
+	Username  string `json:"username,omitempty"`
+	IconEmoji string `json:"icon_emoji,omitempty"`
+}
+
+// slackNotifier posts the notification to a Slack compatible incoming webhook
+type slackNotifier struct {
+	name string
+	conf slackNotifierConf
+}
+
+func (n *slackNotifier) GetType() string {
+	return slackNotifierType
+}
+
+func (n *slackNotifier) GetName() string {
+	return n.name
+}
+
+func (n *slackNotifier) Init(name string, input map[string]interface{}) error {
+	var conf slackNotifierConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing notifier '%s' configuration", n.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating notifier '%s' configuration", n.GetType())
+	}
+
+	n.name = name
+	n.conf = conf
+
+	return nil
+}
+
+func (n *slackNotifier) Notify(ctx context.Context, notification *Notification) error {
+	icon := ":red_circle:"
+	if notification.Kind == KindRecovery {
+		icon = ":large_green_circle:"
+	}
+
+	payload := slackPayload{
+		Text:      fmt.Sprintf("%s *%s*\n```%s```", icon, notification.Title(), notification.Text()),
+		Channel:   n.conf.Channel,
+		Username:  n.conf.Username,
+		IconEmoji: n.conf.IconEmoji,
+	}
+
+	return postJSON(ctx, http.MethodPost, n.conf.URL, nil, payload)
+}
@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	tests := []struct {
+		kind  string
+		icon  string
+		title string
+	}{
+		{KindFailure, ":red_circle:", "[prod] flow checkout failed 3 times in a row"},
+		{KindRecovery, ":large_green_circle:", "[prod] flow checkout recovered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			server, requests := newTestServer(t, http.StatusOK)
+
+			n := &slackNotifier{}
+			err := n.Init("slack", map[string]interface{}{
+				"url":       server.URL,
+				"channel":   "#alerts",
+				"username":  "blackbox",
+				"iconEmoji": ":robot_face:",
+			})
+			if err != nil {
+				t.Fatalf("failed initializing notifier: %v", err)
+			}
+
+			if err := n.Notify(context.Background(), newTestNotification(tt.kind)); err != nil {
+				t.Fatalf("failed notifying: %v", err)
+			}
+
+			req := <-requests
+			if req.method != http.MethodPost {
+				t.Errorf("expected method POST got %s", req.method)
+			}
+
+			var payload slackPayload
+			if err := json.Unmarshal(req.body, &payload); err != nil {
+				t.Fatalf("failed decoding payload %s: %v", req.body, err)
+			}
+
+			if payload.Channel != "#alerts" || payload.Username != "blackbox" || payload.IconEmoji != ":robot_face:" {
+				t.Errorf("expected the configured channel, username and icon got %+v", payload)
+			}
+			if !strings.HasPrefix(payload.Text, tt.icon+" *"+tt.title+"*\n```") {
+				t.Errorf("expected text to start with the icon and title got %q", payload.Text)
+			}
+			if !strings.Contains(payload.Text, "Failed step: pay") {
+				t.Errorf("expected text to hold the notification details got %q", payload.Text)
+			}
+		})
+	}
+}
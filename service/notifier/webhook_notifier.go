@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const webhookNotifierType = "webhook"
+
+type webhookNotifierConf struct {
+	URL     string `validate:"required,url"`
+	Method  string `validate:"omitempty,oneof=POST PUT"`
+	Headers map[string]string
+}
+
+// webhookNotifier posts the notification as JSON
+type webhookNotifier struct {
+	name string
+	conf webhookNotifierConf
+}
+
+func (n *webhookNotifier) GetType() string {
+	return webhookNotifierType
+}
+
+func (n *webhookNotifier) GetName() string {
+	return n.name
+}
+
+func (n *webhookNotifier) Init(name string, input map[string]interface{}) error {
+	var conf webhookNotifierConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing notifier '%s' configuration", n.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating notifier '%s' configuration", n.GetType())
+	}
+
+	if conf.Method == "" {
+		conf.Method = http.MethodPost
+	}
+
+	n.name = name
+	n.conf = conf
+
+	return nil
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, notification *Notification) error {
+	return postJSON(ctx, n.conf.Method, n.conf.URL, n.conf.Headers, notification)
+}
+
+// postJSON sends the body as JSON and fails on non 2xx responses
+func postJSON(ctx context.Context, method string, url string, headers map[string]string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "failed encoding notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "failed creating notification request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed sending notification")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("notification request returned status %d: %s", resp.StatusCode, respBody)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestNotification(kind string) *Notification {
+	return &Notification{
+		Kind:                kind,
+		Flow:                "checkout",
+		RunID:               "run-1",
+		Status:              "error",
+		Environment:         "prod",
+		FailedStep:          "pay",
+		Error:               "button not found",
+		ConsecutiveFailures: 3,
+		Time:                time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+// recordedRequest is a request received by the test server
+type recordedRequest struct {
+	method  string
+	headers http.Header
+	body    []byte
+}
+
+// newTestServer records the requests it receives and responds with the status
+func newTestServer(t *testing.T, status int) (*httptest.Server, <-chan recordedRequest) {
+	t.Helper()
+
+	requests := make(chan recordedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- recordedRequest{method: r.Method, headers: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, requests := newTestServer(t, http.StatusNoContent)
+
+	n := &webhookNotifier{}
+	err := n.Init("hook", map[string]interface{}{
+		"url":     server.URL,
+		"method":  "PUT",
+		"headers": map[string]interface{}{"Authorization": "Bearer token"},
+	})
+	if err != nil {
+		t.Fatalf("failed initializing notifier: %v", err)
+	}
+
+	notification := newTestNotification(KindFailure)
+	if err := n.Notify(context.Background(), notification); err != nil {
+		t.Fatalf("failed notifying: %v", err)
+	}
+
+	req := <-requests
+	if req.method != http.MethodPut {
+		t.Errorf("expected method PUT got %s", req.method)
+	}
+	if got := req.headers.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected json content type got %s", got)
+	}
+	if got := req.headers.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("expected the configured header got %s", got)
+	}
+
+	var payload Notification
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("failed decoding payload %s: %v", req.body, err)
+	}
+	if payload != *notification {
+		t.Errorf("expected payload %+v got %+v", *notification, payload)
+	}
+}
+
+func TestWebhookNotifierErrorStatus(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusInternalServerError)
+
+	n := &webhookNotifier{}
+	if err := n.Init("hook", map[string]interface{}{"url": server.URL}); err != nil {
+		t.Fatalf("failed initializing notifier: %v", err)
+	}
+
+	if err := n.Notify(context.Background(), newTestNotification(KindFailure)); err == nil {
+		t.Fatal("expected an error on a 500 response")
+	}
+}
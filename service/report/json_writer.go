@@ -0,0 +1,46 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+)
+
+const jsonWriterType = "json"
+
+type jsonReport struct {
+	GeneratedAt time.Time           `json:"generatedAt"`
+	Passed      bool                `json:"passed"`
+	Total       int                 `json:"total"`
+	Failed      int                 `json:"failed"`
+	Runs        []*tester.RunResult `json:"runs"`
+}
+
+// jsonWriter writes the runs results with a summary
+type jsonWriter struct{}
+
+func (w *jsonWriter) GetType() string {
+	return jsonWriterType
+}
+
+func (w *jsonWriter) Write(out io.Writer, results []*tester.RunResult) error {
+	report := jsonReport{
+		GeneratedAt: time.Now(),
+		Total:       len(results),
+		Runs:        results,
+	}
+
+	for _, result := range results {
+		if !result.Passed() {
+			report.Failed++
+		}
+	}
+	report.Passed = report.Failed == 0
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
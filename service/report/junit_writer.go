@@ -0,0 +1,187 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+)
+
+const junitWriterType = "junit"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	ID         string          `xml:"id,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitWriter writes every flow run as a test suite and its steps as test cases,
+// setup and teardown steps are in the <flow>.setup and <flow>.teardown classes.
+// A failed teardown does not fail the run, its errors are in the step system-err
+type junitWriter struct{}
+
+func (w *junitWriter) GetType() string {
+	return junitWriterType
+}
+
+func (w *junitWriter) Write(out io.Writer, results []*tester.RunResult) error {
+	suites := junitTestSuites{Name: "blackbox-tester"}
+
+	var durationMS float64
+	for _, result := range results {
+		suite := junitSuite(result)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+		durationMS += result.DurationMS
+	}
+	suites.Time = junitSeconds(durationMS)
+
+	_, err := io.WriteString(out, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(suites)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(out, "\n")
+
+	return err
+}
+
+func junitSuite(result *tester.RunResult) junitTestSuite {
+	suite := junitTestSuite{
+		Name:      result.Flow,
+		ID:        result.RunID,
+		Time:      junitSeconds(result.DurationMS),
+		Timestamp: result.StartTime.Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "runId", Value: result.RunID},
+			{Name: "status", Value: result.Status},
+		},
+	}
+
+	if result.Artifacts != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "artifacts", Value: result.Artifacts})
+	}
+
+	if result.TeardownStatus != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "teardownStatus", Value: result.TeardownStatus})
+	}
+
+	stepFailed := false
+	for _, group := range []struct {
+		className string
+		steps     []*tester.StepResult
+		teardown  bool
+	}{
+		{result.Flow + ".setup", result.Setup, false},
+		{result.Flow, result.Steps, false},
+		{result.Flow + ".teardown", result.Teardown, true},
+	} {
+		for _, step := range group.steps {
+			testCase := junitStepCase(group.className, step, group.teardown)
+			if testCase.Failure != nil {
+				suite.Failures++
+				stepFailed = true
+			}
+			if testCase.Skipped != nil {
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+	}
+
+	// runs failing before any step, e.g. when no browser is available
+	if !result.Passed() && !stepFailed {
+		suite.Failures++
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      "run",
+			ClassName: result.Flow,
+			Time:      junitSeconds(result.DurationMS),
+			Failure:   &junitFailure{Message: result.Error, Type: result.Status, Text: result.Error},
+		})
+	}
+
+	suite.Tests = len(suite.Cases)
+
+	return suite
+}
+
+// junitStepCase returns the test case of the step, failed teardown steps pass as they do not fail the run
+func junitStepCase(className string, step *tester.StepResult, teardown bool) junitTestCase {
+	testCase := junitTestCase{
+		Name:      step.Name,
+		ClassName: className,
+		Time:      junitSeconds(step.DurationMS),
+	}
+
+	switch step.Status {
+	case tester.StatusError, tester.StatusTimeout:
+		text := fmt.Sprintf("%s step '%s' %s after %d attempts: %s", step.Type, step.Name, step.Status, step.Attempts, step.Error) //nolint // line length
+		if teardown {
+			testCase.SystemErr = text
+			break
+		}
+		testCase.Failure = &junitFailure{
+			Message: step.Error,
+			Type:    step.Status,
+			Text:    text,
+		}
+	case tester.StatusSkipped:
+		testCase.Skipped = &struct{}{}
+	case tester.StatusWarning:
+		testCase.SystemOut = "warning: " + step.Error
+	}
+
+	return testCase
+}
+
+func junitSeconds(ms float64) string {
+	return fmt.Sprintf("%.3f", ms/1000)
+}
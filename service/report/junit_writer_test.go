@@ -0,0 +1,49 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+)
+
+func TestJunitWriterTeardownFailure(t *testing.T) {
+	result := &tester.RunResult{
+		RunID:     "run-1",
+		Flow:      "checkout",
+		Status:    tester.StatusSuccess,
+		StartTime: time.Now(),
+		Steps: []*tester.StepResult{
+			{Name: "open", Type: "navigate-step", Status: tester.StatusSuccess, Attempts: 1},
+		},
+		Teardown: []*tester.StepResult{
+			{Name: "cleanup", Type: "http-step", Status: tester.StatusError, Attempts: 1, Error: "status 500"},
+		},
+		TeardownStatus: tester.StatusError,
+		TeardownError:  "status 500",
+	}
+
+	var out bytes.Buffer
+	if err := (&junitWriter{}).Write(&out, []*tester.RunResult{result}); err != nil {
+		t.Fatalf("failed writing report: %v", err)
+	}
+	report := out.String()
+
+	if !result.Passed() {
+		t.Fatal("expected the run to pass")
+	}
+	if strings.Contains(report, "<failure") {
+		t.Fatalf("expected no failure for a passed run, got:\n%s", report)
+	}
+	if !strings.Contains(report, `failures="0"`) {
+		t.Fatalf("expected no failures count, got:\n%s", report)
+	}
+	if !strings.Contains(report, "<system-err>http-step step &#39;cleanup&#39; error after 1 attempts: status 500</system-err>") {
+		t.Fatalf("expected the teardown error in system-err, got:\n%s", report)
+	}
+	if !strings.Contains(report, `<property name="teardownStatus" value="error"></property>`) {
+		t.Fatalf("expected the teardown status property, got:\n%s", report)
+	}
+}
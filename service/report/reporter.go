@@ -0,0 +1,117 @@
+package report
+
+import (
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	"github.com/pkg/errors"
+)
+
+// report path placeholders, runs with different expanded paths are written to different files
+const (
+	flowPlaceholder  = "{flow}"
+	runIDPlaceholder = "{runId}"
+)
+
+// reportOutput is a report writer and the path template of its files
+type reportOutput struct {
+	writer WriterInterface
+	path   string
+}
+
+// reporterImpl writes the runs with every configured writer
+type reporterImpl struct {
+	outputs []reportOutput
+
+	lock sync.Mutex // runs finishing together may write the same files
+}
+
+// NewReporter creates a reporter for the "type:path" reports, e.g. "junit:reports/{flow}.xml"
+func NewReporter(writerFactory WriterFactoryInterface, reports []string) (tester.RunReporterInterface, error) {
+	reporter := &reporterImpl{}
+	for _, report := range reports {
+		writerType, reportPath, err := parseReport(report)
+		if err != nil {
+			return nil, err
+		}
+
+		writer, err := writerFactory.NewWriter(writerType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed creating report '%s'", report)
+		}
+
+		reporter.outputs = append(reporter.outputs, reportOutput{writer: writer, path: reportPath})
+	}
+
+	return reporter, nil
+}
+
+// parseReport splits a "type:path" report into its writer type and path template
+func parseReport(report string) (string, string, error) {
+	parts := strings.SplitN(report, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return "", "", errors.Errorf("invalid report '%s', expected type:path", report)
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+func (r *reporterImpl) Report(results []*tester.RunResult) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var reportErrors *multierror.Error
+	for _, output := range r.outputs {
+		for reportPath, pathResults := range groupByPath(output.path, results) {
+			err := write(output.writer, reportPath, pathResults)
+			if err != nil {
+				reportErrors = multierror.Append(reportErrors, errors.Wrapf(err, "failed writing %s report '%s'", output.writer.GetType(), reportPath)) //nolint // line length
+			}
+		}
+	}
+
+	return reportErrors.ErrorOrNil()
+}
+
+// groupByPath expands the path template of every run keeping the runs order
+func groupByPath(pathTemplate string, results []*tester.RunResult) map[string][]*tester.RunResult {
+	grouped := map[string][]*tester.RunResult{}
+	for _, result := range results {
+		reportPath := strings.NewReplacer(
+			flowPlaceholder, result.Flow,
+			runIDPlaceholder, result.RunID,
+		).Replace(pathTemplate)
+		grouped[reportPath] = append(grouped[reportPath], result)
+	}
+
+	return grouped
+}
+
+func write(writer WriterInterface, reportPath string, results []*tester.RunResult) error {
+	err := os.MkdirAll(path.Dir(reportPath), 0755) //nolint:gosec // reports are not secret
+	if err != nil {
+		return err
+	}
+
+	// written aside and renamed so readers never see a partial report
+	tmpPath := reportPath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	err = writer.Write(file, results)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, reportPath)
+}
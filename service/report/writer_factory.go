@@ -0,0 +1,26 @@
+package report
+
+import (
+	"github.com/pkg/errors"
+)
+
+type WriterFactoryInterface interface {
+	NewWriter(writerType string) (WriterInterface, error)
+}
+
+type writerFactoryImpl struct{}
+
+func NewWriterFactory() WriterFactoryInterface {
+	return &writerFactoryImpl{}
+}
+
+func (wf *writerFactoryImpl) NewWriter(writerType string) (WriterInterface, error) {
+	switch writerType {
+	case junitWriterType:
+		return &junitWriter{}, nil
+	case jsonWriterType:
+		return &jsonWriter{}, nil
+	default:
+		return nil, errors.Errorf("Undefined report writer '%s'", writerType)
+	}
+}
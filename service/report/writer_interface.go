@@ -0,0 +1,13 @@
+package report
+
+import (
+	"io"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+)
+
+// WriterInterface encodes the runs into a report format
+type WriterInterface interface {
+	GetType() string
+	Write(w io.Writer, results []*tester.RunResult) error
+}
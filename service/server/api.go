@@ -0,0 +1,188 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	flowsEndpoint        = "/flows"
+	flowsPrefix          = flowsEndpoint + "/"
+	configReloadEndpoint = "/config/reload"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (as *BlackboxServer) registerAPIHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(flowsEndpoint, as.handleFlows)
+	mux.HandleFunc(flowsPrefix, as.handleFlow)
+	mux.HandleFunc(configReloadEndpoint, as.handleConfigReload)
+	as.registerRunsHandlers(mux)
+	as.registerDashboardHandlers(mux)
+}
+
+// handleConfigReload serves POST /config/reload
+func (as *BlackboxServer) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	err := as.configReloader.Reload()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, as.testManager.Flows())
+}
+
+// handleFlows serves GET /flows
+func (as *BlackboxServer) handleFlows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, as.testManager.Flows())
+}
+
+// flowActionMethods are the methods of the flow actions, the flow itself is the empty action
+var flowActionMethods = map[string]string{
+	"":             http.MethodGet,
+	"pause":        http.MethodPost,
+	"resume":       http.MethodPost,
+	"run":          http.MethodPost,
+	"runs/latest":  http.MethodGet,
+	"runs":         http.MethodGet,
+	"runs/summary": http.MethodGet,
+}
+
+// handleFlow serves GET /flows/{name}, POST /flows/{name}/run, POST /flows/{name}/pause,
+// POST /flows/{name}/resume, GET /flows/{name}/runs/latest, GET /flows/{name}/runs and
+// GET /flows/{name}/runs/summary
+func (as *BlackboxServer) handleFlow(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, flowsPrefix), "/")
+	name := parts[0]
+	action := strings.Join(parts[1:], "/")
+
+	method, ok := flowActionMethods[action]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.Errorf("%s %s not found", r.Method, r.URL.Path))
+		return
+	}
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	switch action {
+	case "":
+		as.handleGetFlow(w, name)
+	case "pause":
+		as.handlePauseFlow(w, name, true)
+	case "resume":
+		as.handlePauseFlow(w, name, false)
+	case "run":
+		as.handleRunFlow(w, r, name)
+	case "runs/latest":
+		as.handleLatestRun(w, name)
+	case "runs":
+		as.queryRuns(w, r.URL.Query(), name)
+	case "runs/summary":
+		as.summarizeRuns(w, r.URL.Query(), name)
+	}
+}
+
+func (as *BlackboxServer) handleGetFlow(w http.ResponseWriter, name string) {
+	info, err := as.testManager.GetFlow(name)
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (as *BlackboxServer) handlePauseFlow(w http.ResponseWriter, name string, pause bool) {
+	var err error
+	if pause {
+		err = as.testManager.PauseFlow(name)
+	} else {
+		err = as.testManager.ResumeFlow(name)
+	}
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+
+	as.handleGetFlow(w, name)
+}
+
+func (as *BlackboxServer) handleRunFlow(w http.ResponseWriter, r *http.Request, name string) {
+	wait := false
+	if waitParam := r.URL.Query().Get("wait"); waitParam != "" {
+		var err error
+		wait, err = strconv.ParseBool(waitParam)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid wait parameter"))
+			return
+		}
+	}
+
+	result, err := as.testManager.RunFlow(name, wait)
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+
+	if !wait {
+		writeJSON(w, http.StatusAccepted, result)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (as *BlackboxServer) handleLatestRun(w http.ResponseWriter, name string) {
+	result, err := as.testManager.LatestRun(name)
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeManagerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, tester.ErrFlowNotFound), errors.Is(err, tester.ErrNoRuns):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, tester.ErrFlowRunning):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.WithError(err).Error("failed writing response")
+	}
+}
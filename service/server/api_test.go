@@ -0,0 +1,277 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/history"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	"github.com/pkg/errors"
+)
+
+// fakeManager serves the flows from a map, the flow 'busy' is always running
+type fakeManager struct {
+	tester.ManagerInterface
+
+	flows map[string]*tester.FlowInfo
+}
+
+func (m *fakeManager) Flows() []tester.FlowInfo {
+	var flows []tester.FlowInfo
+	for _, name := range []string{"busy", "login"} {
+		flows = append(flows, *m.flows[name])
+	}
+
+	return flows
+}
+
+func (m *fakeManager) GetFlow(name string) (*tester.FlowInfo, error) {
+	info, ok := m.flows[name]
+	if !ok {
+		return nil, errors.Wrapf(tester.ErrFlowNotFound, "flow '%s'", name)
+	}
+
+	return info, nil
+}
+
+func (m *fakeManager) PauseFlow(name string) error {
+	info, err := m.GetFlow(name)
+	if err != nil {
+		return err
+	}
+	info.Paused = true
+
+	return nil
+}
+
+func (m *fakeManager) ResumeFlow(name string) error {
+	info, err := m.GetFlow(name)
+	if err != nil {
+		return err
+	}
+	info.Paused = false
+
+	return nil
+}
+
+func (m *fakeManager) RunFlow(name string, wait bool) (*tester.RunResult, error) {
+	if _, err := m.GetFlow(name); err != nil {
+		return nil, err
+	}
+	if name == "busy" {
+		return nil, tester.ErrFlowRunning
+	}
+	if !wait {
+		return &tester.RunResult{RunID: "run-new"}, nil
+	}
+
+	return &tester.RunResult{RunID: "run-new", Flow: name, Status: "success"}, nil
+}
+
+func (m *fakeManager) LatestRun(name string) (*tester.RunResult, error) {
+	info, err := m.GetFlow(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.LastRun == nil {
+		return nil, tester.ErrNoRuns
+	}
+
+	return info.LastRun, nil
+}
+
+// fakeStore serves the runs from a slice and records the last query
+type fakeStore struct {
+	runs      []*tester.RunResult
+	lastQuery history.Query
+}
+
+func (s *fakeStore) Save(result *tester.RunResult) error {
+	s.runs = append(s.runs, result)
+	return nil
+}
+
+func (s *fakeStore) Get(runID string) (*tester.RunResult, error) {
+	for _, run := range s.runs {
+		if run.RunID == runID {
+			return run, nil
+		}
+	}
+
+	return nil, errors.Wrapf(history.ErrRunNotFound, "run '%s'", runID)
+}
+
+func (s *fakeStore) Query(query history.Query) ([]*tester.RunResult, error) {
+	s.lastQuery = query
+
+	results := []*tester.RunResult{}
+	for _, run := range s.runs {
+		if (query.Flow == "" || run.Flow == query.Flow) && (query.Status == "" || run.Status == query.Status) {
+			results = append(results, run)
+		}
+	}
+
+	return results, nil
+}
+
+func (s *fakeStore) CountByStatus(query history.Query) (map[string]int, error) {
+	s.lastQuery = query
+
+	counts := map[string]int{}
+	for _, run := range s.runs {
+		if query.Flow == "" || run.Flow == query.Flow {
+			counts[run.Status]++
+		}
+	}
+
+	return counts, nil
+}
+
+func (s *fakeStore) Start() {}
+
+func (s *fakeStore) Close() error {
+	return nil
+}
+
+type fakeReloader struct {
+	tester.ConfigReloaderInterface
+
+	err error
+}
+
+func (r *fakeReloader) Reload() error {
+	return r.err
+}
+
+func newTestServer(runHistory history.StoreInterface, reloader *fakeReloader) *BlackboxServer {
+	manager := &fakeManager{flows: map[string]*tester.FlowInfo{
+		"login": {Name: "login", Steps: []string{"open"}, LastRun: &tester.RunResult{RunID: "run-2", Flow: "login", Status: "error"}}, //nolint // line length
+		"busy":  {Name: "busy", Steps: []string{"wait"}},
+	}}
+
+	return NewBlackboxServer(&config.ServerSettings{}, &config.TesterSettings{}, manager, reloader, runHistory)
+}
+
+func newTestStore() *fakeStore {
+	start := time.Date(2020, 10, 1, 3, 0, 0, 0, time.UTC)
+
+	return &fakeStore{runs: []*tester.RunResult{
+		{RunID: "run-1", Flow: "login", Status: "success", StartTime: start},
+		{RunID: "run-2", Flow: "login", Status: "error", StartTime: start.Add(time.Minute)},
+		{RunID: "run-3", Flow: "busy", Status: "warning", StartTime: start.Add(2 * time.Minute)},
+	}}
+}
+
+func TestAPIHandlers(t *testing.T) {
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{http.MethodGet, "/flows", http.StatusOK, `"name":"busy"`},
+		{http.MethodPost, "/flows", http.StatusMethodNotAllowed, "method POST not allowed"},
+		{http.MethodGet, "/flows/login", http.StatusOK, `"name":"login"`},
+		{http.MethodGet, "/flows/missing", http.StatusNotFound, "flow not found"},
+		{http.MethodDelete, "/flows/login", http.StatusMethodNotAllowed, "method DELETE not allowed"},
+		{http.MethodGet, "/flows/login/unknown", http.StatusNotFound, "GET /flows/login/unknown not found"},
+		{http.MethodPost, "/flows/login/pause", http.StatusOK, `"paused":true`},
+		{http.MethodGet, "/flows/login/pause", http.StatusMethodNotAllowed, "method GET not allowed"},
+		{http.MethodPost, "/flows/missing/pause", http.StatusNotFound, "flow not found"},
+		{http.MethodPost, "/flows/login/resume", http.StatusOK, `"paused":false`},
+		{http.MethodPost, "/flows/missing/resume", http.StatusNotFound, "flow not found"},
+		{http.MethodPost, "/flows/login/run", http.StatusAccepted, `"runId":"run-new"`},
+		{http.MethodPost, "/flows/login/run?wait=true", http.StatusOK, `"status":"success"`},
+		{http.MethodPost, "/flows/login/run?wait=soon", http.StatusBadRequest, "invalid wait parameter"},
+		{http.MethodPost, "/flows/busy/run", http.StatusConflict, "flow is already running"},
+		{http.MethodPost, "/flows/missing/run", http.StatusNotFound, "flow not found"},
+		{http.MethodGet, "/flows/login/run", http.StatusMethodNotAllowed, "method GET not allowed"},
+		{http.MethodGet, "/flows/login/runs/latest", http.StatusOK, `"runId":"run-2"`},
+		{http.MethodGet, "/flows/busy/runs/latest", http.StatusNotFound, "flow did not run yet"},
+		{http.MethodGet, "/flows/missing/runs/latest", http.StatusNotFound, "flow not found"},
+		{http.MethodGet, "/flows/login/runs", http.StatusOK, `"runId":"run-1"`},
+		{http.MethodGet, "/flows/login/runs?status=error", http.StatusOK, `[{"runId":"run-2"`},
+		{http.MethodGet, "/flows/login/runs?limit=0", http.StatusBadRequest, "invalid limit parameter"},
+		{http.MethodGet, "/flows/login/runs?from=yesterday", http.StatusBadRequest, "invalid from parameter"},
+		{http.MethodPost, "/flows/login/runs", http.StatusMethodNotAllowed, "method POST not allowed"},
+		{http.MethodGet, "/flows/login/runs/summary", http.StatusOK, `{"flow":"login","total":2,"passed":1,"statuses":{"error":1,"success":1}}`}, //nolint // line length
+		{http.MethodGet, "/flows/login/runs/summary?to=later", http.StatusBadRequest, "invalid to parameter"},
+		{http.MethodGet, "/runs", http.StatusOK, `"runId":"run-3"`},
+		{http.MethodPost, "/runs", http.StatusMethodNotAllowed, "method POST not allowed"},
+		{http.MethodGet, "/runs/run-1", http.StatusOK, `"runId":"run-1"`},
+		{http.MethodGet, "/runs/run-9", http.StatusNotFound, "run not found"},
+		{http.MethodDelete, "/runs/run-1", http.StatusMethodNotAllowed, "method DELETE not allowed"},
+		{http.MethodPost, "/config/reload", http.StatusOK, `"name":"login"`},
+		{http.MethodGet, "/config/reload", http.StatusMethodNotAllowed, "method GET not allowed"},
+	}
+
+	server := newTestServer(newTestStore(), &fakeReloader{})
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.Handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d: %s", tt.wantStatus, recorder.Code, recorder.Body.String())
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantBody) {
+				t.Fatalf("expected body containing %q got %q", tt.wantBody, recorder.Body.String())
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Fatalf("expected a json response got %s", contentType)
+			}
+		})
+	}
+}
+
+func TestAPIRunsQuery(t *testing.T) {
+	store := newTestStore()
+	server := newTestServer(store, &fakeReloader{})
+
+	path := "/runs?flow=login&status=error&from=2020-10-01T03:00:00Z&to=2020-10-01T03:30:00Z&limit=5"
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	want := history.Query{
+		Flow:   "login",
+		Status: "error",
+		From:   time.Date(2020, 10, 1, 3, 0, 0, 0, time.UTC),
+		To:     time.Date(2020, 10, 1, 3, 30, 0, 0, time.UTC),
+		Limit:  5,
+	}
+	if got := store.lastQuery; !got.From.Equal(want.From) || !got.To.Equal(want.To) ||
+		got.Flow != want.Flow || got.Status != want.Status || got.Limit != want.Limit {
+		t.Fatalf("expected query %+v got %+v", want, got)
+	}
+}
+
+func TestAPIWithoutHistory(t *testing.T) {
+	server := newTestServer(nil, &fakeReloader{})
+
+	for _, path := range []string{"/runs", "/runs/run-1", "/flows/login/runs", "/flows/login/runs/summary"} {
+		recorder := httptest.NewRecorder()
+		server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), errHistoryDisabled.Error()) {
+			t.Errorf("%s: expected the history disabled error got %d %s", path, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestAPIConfigReloadError(t *testing.T) {
+	server := newTestServer(newTestStore(), &fakeReloader{err: errors.New("config.yaml:3:1: unknown key 'flow'")})
+
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/config/reload", nil))
+
+	if recorder.Code != http.StatusUnprocessableEntity || !strings.Contains(recorder.Body.String(), "unknown key 'flow'") {
+		t.Fatalf("expected the reload error got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dashboardPrefix = "/dashboard/"
+	artifactsPrefix = "/artifacts/"
+)
+
+// dashboardFiles are the dashboard static assets, the dashboard reads the flows and runs from the API
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+func (as *BlackboxServer) registerDashboardHandlers(mux *http.ServeMux) {
+	// the embedded folder always exists
+	assets, _ := fs.Sub(dashboardFiles, "dashboard")
+	mux.Handle(dashboardPrefix, http.StripPrefix(dashboardPrefix, http.FileServer(http.FS(assets))))
+	mux.HandleFunc("/", as.handleRoot)
+
+	// serves the run artifacts so the dashboard can show the failed runs screenshots and logs
+	if as.testerSettings.ArtifactsFolder != "" {
+		mux.Handle(artifactsPrefix, http.StripPrefix(artifactsPrefix, artifactsHandler(as.testerSettings.ArtifactsFolder)))
+	}
+}
+
+// artifactsHandler serves the run folders {flow}/{runId}/ and their files only,
+// the artifacts folder and the flow folders are not listed so the runs can not be browsed
+func artifactsHandler(artifactsFolder string) http.Handler {
+	files := http.FileServer(http.Dir(artifactsFolder))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segments := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+		if len(segments) < 2 || len(segments) > 3 {
+			writeError(w, http.StatusNotFound, errors.Errorf("%s %s not found", r.Method, r.URL.Path))
+			return
+		}
+
+		for _, segment := range segments {
+			if segment == "" || segment == "." || segment == ".." {
+				writeError(w, http.StatusNotFound, errors.Errorf("%s %s not found", r.Method, r.URL.Path))
+				return
+			}
+		}
+
+		files.ServeHTTP(w, r)
+	})
+}
+
+// handleRoot redirects GET / to the dashboard
+func (as *BlackboxServer) handleRoot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(w, http.StatusNotFound, errors.Errorf("%s %s not found", r.Method, r.URL.Path))
+		return
+	}
+
+	http.Redirect(w, r, dashboardPrefix, http.StatusFound)
+}
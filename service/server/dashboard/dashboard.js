@@ -0,0 +1,270 @@
+'use strict';
+
+// uptime window and number of runs shown in the timeline and sparklines
+const UPTIME_WINDOW_MS = 24 * 60 * 60 * 1000;
+const RECENT_RUNS = 30;
+const REFRESH_MS = 30 * 1000;
+
+const content = document.getElementById('content');
+let refreshTimer;
+
+function escapeHTML(value) {
+  return String(value == null ? '' : value)
+    .replace(/&/g, '&amp;')
+    .replace(/</g, '&lt;')
+    .replace(/>/g, '&gt;')
+    .replace(/"/g, '&quot;')
+    .replace(/'/g, '&#39;');
+}
+
+async function getJSON(url) {
+  const response = await fetch(url);
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+async function getText(url) {
+  const response = await fetch(url);
+  return response.ok ? response.text() : null;
+}
+
+function statusBadge(status) {
+  const name = status || 'unknown';
+  return `<span class="status ${escapeHTML(name)}">${escapeHTML(name)}</span>`;
+}
+
+function passed(run) {
+  return run.status === 'success' || run.status === 'warning';
+}
+
+function formatDuration(ms) {
+  if (ms == null) {
+    return '';
+  }
+  return ms < 1000 ? `${Math.round(ms)}ms` : `${(ms / 1000).toFixed(1)}s`;
+}
+
+function formatTime(time) {
+  return time ? new Date(time).toLocaleString() : '';
+}
+
+function sparkline(values) {
+  const width = 120;
+  const height = 20;
+  if (values.length < 2) {
+    return `<svg width="${width}" height="${height}"></svg>`;
+  }
+
+  const max = Math.max(...values) || 1;
+  const points = values.map((value, i) => {
+    const x = (i / (values.length - 1)) * width;
+    const y = height - (value / max) * (height - 2) - 1;
+    return `${x.toFixed(1)},${y.toFixed(1)}`;
+  });
+
+  return `<svg width="${width}" height="${height}"><polyline points="${points.join(' ')}"/></svg>`;
+}
+
+// stepSparklines returns the latency sparkline of every step, runs are sorted oldest first
+function stepSparklines(runs) {
+  const durations = new Map();
+  for (const run of runs) {
+    for (const step of [...(run.setup || []), ...(run.steps || [])]) {
+      if (step.status === 'skipped') {
+        continue;
+      }
+      if (!durations.has(step.name)) {
+        durations.set(step.name, []);
+      }
+      durations.get(step.name).push(step.durationMs);
+    }
+  }
+
+  const lines = [];
+  for (const [name, values] of durations) {
+    const last = values[values.length - 1];
+    lines.push(`<div><span>${escapeHTML(name)} ${formatDuration(last)}</span>${sparkline(values)}</div>`);
+  }
+  return `<div class="sparklines">${lines.join('')}</div>`;
+}
+
+function timeline(runs) {
+  const items = runs.map((run) => `<a class="${escapeHTML(run.status)}" href="#/runs/${encodeURIComponent(run.runId)}"
+    title="${escapeHTML(formatTime(run.startTime))} ${escapeHTML(run.status)}"></a>`);
+  return `<div class="timeline">${items.join('')}</div>`;
+}
+
+function flowStatus(flow) {
+  if (flow.paused) {
+    return '<span class="status paused">paused</span>';
+  }
+  if (flow.inMaintenance) {
+    return '<span class="status paused">maintenance</span>';
+  }
+  return statusBadge(flow.lastRun && flow.lastRun.status);
+}
+
+async function flowRow(flow, historyEnabled) {
+  let runs = [];
+  let summary = null;
+  let lastError;
+  if (historyEnabled) {
+    // the uptime counts all the runs of the window, only the recent runs are fetched
+    const name = encodeURIComponent(flow.name);
+    const from = new Date(Date.now() - UPTIME_WINDOW_MS).toISOString();
+    const [recentRuns, runsSummary, errors, timeouts] = await Promise.all([
+      getJSON(`/flows/${name}/runs?from=${from}&limit=${RECENT_RUNS}`),
+      getJSON(`/flows/${name}/runs/summary?from=${from}`),
+      getJSON(`/flows/${name}/runs?status=error&from=${from}&limit=1`),
+      getJSON(`/flows/${name}/runs?status=timeout&from=${from}&limit=1`),
+    ]);
+    runs = recentRuns;
+    summary = runsSummary;
+    lastError = errors.concat(timeouts).sort((a, b) => new Date(b.startTime) - new Date(a.startTime))[0];
+  } else if (flow.lastRun) {
+    runs = [flow.lastRun];
+  }
+
+  const finished = runs.filter((run) => run.status !== 'running');
+  if (!summary) {
+    summary = { total: finished.length, passed: finished.filter(passed).length };
+    lastError = finished.find((run) => !passed(run));
+  }
+
+  const uptime = summary.total
+    ? `${((summary.passed / summary.total) * 100).toFixed(2)}%`
+    : '<span class="muted">no runs</span>';
+  const recent = finished.slice(0, RECENT_RUNS).reverse();
+
+  return `<tr>
+    <td><strong>${escapeHTML(flow.name)}</strong><div class="muted">${escapeHTML(flow.schedule)}</div></td>
+    <td>${flowStatus(flow)}</td>
+    <td>${uptime}</td>
+    <td>${timeline(recent)}</td>
+    <td>${stepSparklines(recent)}</td>
+    <td class="last-error">${lastError
+    ? `<a href="#/runs/${encodeURIComponent(lastError.runId)}">${escapeHTML(formatTime(lastError.startTime))}</a>
+       ${escapeHTML(lastError.error)}`
+    : ''}</td>
+  </tr>`;
+}
+
+async function renderFlows() {
+  const flows = await getJSON('/flows');
+
+  // the run history may be disabled, the timeline then only shows the last run
+  let historyEnabled = true;
+  try {
+    await getJSON('/runs?limit=1');
+  } catch (e) {
+    historyEnabled = false;
+  }
+
+  const rows = await Promise.all(flows.map((flow) => flowRow(flow, historyEnabled)));
+
+  content.innerHTML = `
+    ${historyEnabled ? '' : '<p class="muted">The run history is disabled, only the last runs are shown</p>'}
+    <table>
+      <tr>
+        <th>Flow</th><th>Status</th><th>Uptime (24h)</th><th>Recent runs</th><th>Step latency</th><th>Last error</th>
+      </tr>
+      ${rows.join('')}
+    </table>`;
+
+  refreshTimer = setTimeout(render, REFRESH_MS);
+}
+
+function stepsTable(title, steps) {
+  if (!steps || steps.length === 0) {
+    return '';
+  }
+
+  const rows = steps.map((step) => `<tr>
+    <td>${escapeHTML(step.name)}</td>
+    <td>${escapeHTML(step.type)}</td>
+    <td>${statusBadge(step.status)}</td>
+    <td>${step.attempts || ''}</td>
+    <td>${formatDuration(step.durationMs)}</td>
+    <td class="last-error">${escapeHTML(step.error)}</td>
+  </tr>`);
+
+  return `<h2>${title}</h2>
+    <table>
+      <tr><th>Step</th><th>Type</th><th>Status</th><th>Attempts</th><th>Duration</th><th>Error</th></tr>
+      ${rows.join('')}
+    </table>`;
+}
+
+async function renderArtifacts(run) {
+  const folder = `/artifacts/${encodeURIComponent(run.flow)}/${encodeURIComponent(run.runId)}/`;
+  const [errorText, consoleLog, url] = await Promise.all([
+    getText(`${folder}error.txt`),
+    getText(`${folder}console.log`),
+    getText(`${folder}url.txt`),
+  ]);
+
+  if (errorText == null) {
+    return run.artifacts ? `<h2>Artifacts</h2><p>${escapeHTML(run.artifacts)}</p>` : '';
+  }
+
+  return `<h2>Artifacts</h2>
+    <p><a href="${folder}">All files</a>${url ? ` &middot; page ${escapeHTML(url)}` : ''}</p>
+    <img class="screenshot" src="${folder}screenshot.png" alt="screenshot" onerror="this.remove()">
+    ${consoleLog ? `<h2>Console</h2><pre>${escapeHTML(consoleLog)}</pre>` : ''}`;
+}
+
+async function renderRun(runId) {
+  let run;
+  try {
+    run = await getJSON(`/runs/${encodeURIComponent(runId)}`);
+  } catch (e) {
+    // runs of the current process are available without the run history
+    const flows = await getJSON('/flows');
+    const flow = flows.find((f) => f.lastRun && f.lastRun.runId === runId);
+    if (!flow) {
+      throw e;
+    }
+    run = flow.lastRun;
+  }
+
+  content.innerHTML = `
+    <p><a href="#/">&larr; Flows</a></p>
+    <dl>
+      <dt>Flow</dt><dd>${escapeHTML(run.flow)}</dd>
+      <dt>Run</dt><dd>${escapeHTML(run.runId)}</dd>
+      <dt>Status</dt><dd>${statusBadge(run.status)}</dd>
+      <dt>Started</dt><dd>${escapeHTML(formatTime(run.startTime))}</dd>
+      <dt>Duration</dt><dd>${formatDuration(run.durationMs)}</dd>
+      ${run.error ? `<dt>Error</dt><dd class="last-error">${escapeHTML(run.error)}</dd>` : ''}
+      ${run.teardownStatus ? `<dt>Teardown</dt><dd>${statusBadge(run.teardownStatus)}
+        ${escapeHTML(run.teardownError)}</dd>` : ''}
+    </dl>
+    ${stepsTable('Setup', run.setup)}
+    ${stepsTable('Steps', run.steps)}
+    ${stepsTable('Teardown', run.teardown)}
+    <div id="artifacts"></div>`;
+
+  document.getElementById('artifacts').innerHTML = await renderArtifacts(run);
+}
+
+async function render() {
+  clearTimeout(refreshTimer);
+
+  const match = window.location.hash.match(/^#\/runs\/(.+)$/);
+  try {
+    if (match) {
+      await renderRun(decodeURIComponent(match[1]));
+    } else {
+      await renderFlows();
+    }
+    document.getElementById('updated').textContent = `Updated ${new Date().toLocaleTimeString()}`;
+  } catch (e) {
+    content.innerHTML = `<p class="last-error">${escapeHTML(e.message)}</p>`;
+  }
+}
+
+window.addEventListener('hashchange', render);
+render();
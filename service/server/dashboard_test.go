@@ -0,0 +1,53 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestArtifactsHandler(t *testing.T) {
+	artifactsFolder := t.TempDir()
+	runFolder := path.Join(artifactsFolder, "login", "run-1")
+	if err := os.MkdirAll(runFolder, 0750); err != nil {
+		t.Fatalf("failed creating run folder: %v", err)
+	}
+	if err := ioutil.WriteFile(path.Join(runFolder, "error.txt"), []byte("step failed"), 0600); err != nil {
+		t.Fatalf("failed writing artifact: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(artifactsPrefix, http.StripPrefix(artifactsPrefix, artifactsHandler(artifactsFolder)))
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/artifacts/login/run-1/error.txt", http.StatusOK, "step failed"},
+		{"/artifacts/login/run-1/", http.StatusOK, "error.txt"},
+		{"/artifacts/login/run-1/missing.txt", http.StatusNotFound, ""},
+		{"/artifacts/", http.StatusNotFound, ""},
+		{"/artifacts/login/", http.StatusNotFound, ""},
+		{"/artifacts/login", http.StatusNotFound, ""},
+		{"/artifacts/login/run-1/error.txt/more", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d: %s", tt.wantStatus, recorder.Code, recorder.Body.String())
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantBody) {
+				t.Fatalf("expected body containing %q got %q", tt.wantBody, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/history"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
+	"github.com/pkg/errors"
+)
+
+const (
+	runsEndpoint = "/runs"
+	runsPrefix   = runsEndpoint + "/"
+)
+
+var errHistoryDisabled = errors.New("run history is disabled")
+
+// runsSummary counts the stored runs by status, passed runs are the successful and warning runs
+type runsSummary struct {
+	Flow     string         `json:"flow"`
+	Total    int            `json:"total"`
+	Passed   int            `json:"passed"`
+	Statuses map[string]int `json:"statuses"`
+}
+
+func (as *BlackboxServer) registerRunsHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(runsEndpoint, as.handleRuns)
+	mux.HandleFunc(runsPrefix, as.handleRun)
+}
+
+// handleRuns serves GET /runs?flow=&status=&from=&to=&limit=, from and to are RFC3339 times
+func (as *BlackboxServer) handleRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	as.queryRuns(w, r.URL.Query(), r.URL.Query().Get("flow"))
+}
+
+// handleRun serves GET /runs/{runId}
+func (as *BlackboxServer) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	if as.runHistory == nil {
+		writeError(w, http.StatusNotFound, errHistoryDisabled)
+		return
+	}
+
+	result, err := as.runHistory.Get(strings.TrimPrefix(r.URL.Path, runsPrefix))
+	if err != nil {
+		if errors.Is(err, history.ErrRunNotFound) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// queryRuns writes the runs of the flow, or of all the flows when empty, matching the query parameters
+func (as *BlackboxServer) queryRuns(w http.ResponseWriter, params url.Values, flow string) {
+	if as.runHistory == nil {
+		writeError(w, http.StatusNotFound, errHistoryDisabled)
+		return
+	}
+
+	query, err := parseRunsQuery(params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	query.Flow = flow
+
+	results, err := as.runHistory.Query(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+// summarizeRuns writes the number of runs of the flow by status matching the query parameters,
+// unlike queryRuns all the runs of the time range are counted
+func (as *BlackboxServer) summarizeRuns(w http.ResponseWriter, params url.Values, flow string) {
+	if as.runHistory == nil {
+		writeError(w, http.StatusNotFound, errHistoryDisabled)
+		return
+	}
+
+	query, err := parseRunsQuery(params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	query.Flow = flow
+
+	counts, err := as.runHistory.CountByStatus(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	summary := runsSummary{Flow: flow, Statuses: counts}
+	for status, count := range counts {
+		summary.Total += count
+		if (&tester.RunResult{Status: status}).Passed() {
+			summary.Passed += count
+		}
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func parseRunsQuery(params url.Values) (history.Query, error) {
+	query := history.Query{
+		Status: params.Get("status"),
+	}
+
+	var err error
+	if from := params.Get("from"); from != "" {
+		query.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query, errors.Wrap(err, "invalid from parameter")
+		}
+	}
+
+	if to := params.Get("to"); to != "" {
+		query.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query, errors.Wrap(err, "invalid to parameter")
+		}
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > history.MaxQueryLimit {
+			return query, errors.Errorf("invalid limit parameter, expected 1 to %d", history.MaxQueryLimit)
+		}
+	}
+
+	return query, nil
+}
@@ -3,6 +3,8 @@ package server
 import (
 	"context"
 	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/history"
+	"github.com/orensho/thin-slack-blackbox-tester/service/tester"
 	log "github.com/sirupsen/logrus"
 	"net/http"
 	"os"
@@ -17,9 +19,19 @@ type BlackboxServer struct {
 	isInShutdown uint32
 
 	serverSettings *config.ServerSettings
+	testerSettings *config.TesterSettings
+	testManager    tester.ManagerInterface
+	configReloader tester.ConfigReloaderInterface
+	runHistory     history.StoreInterface // nil when the run history is disabled
 }
 
-func NewBlackboxServer(serverSettings *config.ServerSettings) *BlackboxServer {
+func NewBlackboxServer(
+	serverSettings *config.ServerSettings,
+	testerSettings *config.TesterSettings,
+	testManager tester.ManagerInterface,
+	configReloader tester.ConfigReloaderInterface,
+	runHistory history.StoreInterface,
+) *BlackboxServer {
 	log.Info("Starting thin-blackbox-tester ...")
 
 	server := &BlackboxServer{
@@ -28,8 +40,16 @@ func NewBlackboxServer(serverSettings *config.ServerSettings) *BlackboxServer {
 		},
 		shutdownChan:   make(chan bool),
 		serverSettings: serverSettings,
+		testerSettings: testerSettings,
+		testManager:    testManager,
+		configReloader: configReloader,
+		runHistory:     runHistory,
 	}
 
+	mux := http.NewServeMux()
+	server.registerAPIHandlers(mux)
+	server.Handler = mux
+
 	return server
 }
 
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/orensho/thin-slack-blackbox-tester/service/config"
 	"github.com/pkg/errors"
@@ -15,12 +16,45 @@ type MetricsServiceInterface interface {
 	ReportStepTestError(ctx context.Context, flowName string, stepName string) error
 	ReportStepTestTimeout(ctx context.Context, flowName string, stepName string) error
 	ReportStepTestDuration(ctx context.Context, flowName string, ms float64, stepName string) error
+	ReportStepTestRetry(ctx context.Context, flowName string, stepName string) error
+	ReportStepPhaseDuration(ctx context.Context, flowName string, stepName string, phase string, ms float64) error
+	ReportCertificateExpiry(ctx context.Context, flowName string, stepName string, days float64) error
+	ReportFlowPaused(ctx context.Context, flowName string, paused bool) error
+	ReportConfigReloadSuccess(ctx context.Context) error
+	ReportConfigReloadFailure(ctx context.Context) error
+	ReportFlowDuration(ctx context.Context, flowName string, ms float64) error
+	ReportFlowResult(ctx context.Context, flowName string, result string) error
+	ReportFlowLastRun(ctx context.Context, flowName string, runTime time.Time) error
+	ReportFlowLastSuccess(ctx context.Context, flowName string, runTime time.Time) error
+	ReportFlowProbeSuccess(ctx context.Context, flowName string, success bool) error
+	ReportFlowTeardownFailure(ctx context.Context, flowName string) error
+	ReportBrowserPoolUsage(ctx context.Context, pool string, inUse int, capacity int) error
+	ReportBrowserPoolWait(ctx context.Context, pool string, ms float64) error
+	ReportBrowserRecycle(ctx context.Context, pool string, reason string) error
 }
 
+// flow run results
+const (
+	FlowResultSuccess = "success"
+	FlowResultFailure = "failure"
+	FlowResultTimeout = "timeout"
+	FlowResultWarning = "warning"
+)
+
+// browser recycle reasons
+const (
+	BrowserRecycleRuns      = "runs"
+	BrowserRecycleUnhealthy = "unhealthy"
+)
+
 var (
 	keyFlow        = tag.MustNewKey("flow")
 	keyStep        = tag.MustNewKey("step")
 	keyEnvironment = tag.MustNewKey("environment")
+	keyResult      = tag.MustNewKey("result")
+	keyReason      = tag.MustNewKey("reason")
+	keyPool        = tag.MustNewKey("pool")
+	keyPhase       = tag.MustNewKey("phase")
 )
 
 type metricsService struct {
@@ -29,6 +63,22 @@ type metricsService struct {
 	testsStepSuccess  *stats.Int64Measure
 	testsStepTimeout  *stats.Int64Measure
 	testsStepDuration *stats.Float64Measure
+	testsStepRetry    *stats.Int64Measure
+	testsStepPhase    *stats.Float64Measure
+	certificateExpiry *stats.Float64Measure
+	flowPaused        *stats.Int64Measure
+	reloadSuccess     *stats.Int64Measure
+	reloadFailure     *stats.Int64Measure
+	flowDuration      *stats.Float64Measure
+	flowRuns          *stats.Int64Measure
+	flowLastRun       *stats.Float64Measure
+	flowLastSuccess   *stats.Float64Measure
+	flowProbeSuccess  *stats.Int64Measure
+	flowTeardownError *stats.Int64Measure
+	browserPoolInUse  *stats.Int64Measure
+	browserPoolSize   *stats.Int64Measure
+	browserPoolWait   *stats.Float64Measure
+	browserRecycles   *stats.Int64Measure
 }
 
 func NewMetricsService(settings *config.MetricsSettings) (MetricsServiceInterface, error) {
@@ -88,6 +138,217 @@ func (s *metricsService) ReportStepTestDuration(ctx context.Context, flowName st
 	return nil
 }
 
+func (s *metricsService) ReportStepTestRetry(ctx context.Context, flowName string, stepName string) error { //nolint // line length
+	ctx, err := s.createStepMeasurementContext(ctx, flowName, stepName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.testsStepRetry.M(1))
+
+	return nil
+}
+
+func (s *metricsService) ReportStepPhaseDuration(ctx context.Context, flowName string, stepName string, phase string, ms float64) error { //nolint // line length
+	ctx, err := s.createStepMeasurementContext(ctx, flowName, stepName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	ctx, err = tag.New(ctx, tag.Upsert(keyPhase, phase))
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.testsStepPhase.M(ms))
+
+	return nil
+}
+
+func (s *metricsService) ReportCertificateExpiry(ctx context.Context, flowName string, stepName string, days float64) error { //nolint // line length
+	ctx, err := s.createStepMeasurementContext(ctx, flowName, stepName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.certificateExpiry.M(days))
+
+	return nil
+}
+
+func (s *metricsService) ReportFlowPaused(ctx context.Context, flowName string, paused bool) error {
+	ctx, err := s.createFlowMeasurementContext(ctx, flowName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	var value int64
+	if paused {
+		value = 1
+	}
+
+	stats.Record(ctx, s.flowPaused.M(value))
+
+	return nil
+}
+
+func (s *metricsService) ReportConfigReloadSuccess(ctx context.Context) error {
+	ctx, err := s.createMeasurementContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.reloadSuccess.M(1))
+
+	return nil
+}
+
+func (s *metricsService) ReportConfigReloadFailure(ctx context.Context) error {
+	ctx, err := s.createMeasurementContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.reloadFailure.M(1))
+
+	return nil
+}
+
+func (s *metricsService) ReportFlowDuration(ctx context.Context, flowName string, ms float64) error {
+	ctx, err := s.createFlowMeasurementContext(ctx, flowName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.flowDuration.M(ms))
+
+	return nil
+}
+
+func (s *metricsService) ReportFlowResult(ctx context.Context, flowName string, result string) error {
+	ctx, err := s.createFlowMeasurementContext(ctx, flowName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	ctx, err = tag.New(ctx, tag.Upsert(keyResult, result))
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.flowRuns.M(1))
+
+	return nil
+}
+
+func (s *metricsService) ReportFlowLastRun(ctx context.Context, flowName string, runTime time.Time) error {
+	ctx, err := s.createFlowMeasurementContext(ctx, flowName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.flowLastRun.M(unixSeconds(runTime)))
+
+	return nil
+}
+
+func (s *metricsService) ReportFlowLastSuccess(ctx context.Context, flowName string, runTime time.Time) error {
+	ctx, err := s.createFlowMeasurementContext(ctx, flowName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.flowLastSuccess.M(unixSeconds(runTime)))
+
+	return nil
+}
+
+func (s *metricsService) ReportFlowProbeSuccess(ctx context.Context, flowName string, success bool) error {
+	ctx, err := s.createFlowMeasurementContext(ctx, flowName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	var value int64
+	if success {
+		value = 1
+	}
+
+	stats.Record(ctx, s.flowProbeSuccess.M(value))
+
+	return nil
+}
+
+func (s *metricsService) ReportBrowserPoolUsage(ctx context.Context, pool string, inUse int, capacity int) error { //nolint // line length
+	ctx, err := s.createPoolMeasurementContext(ctx, pool)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.browserPoolInUse.M(int64(inUse)), s.browserPoolSize.M(int64(capacity)))
+
+	return nil
+}
+
+func (s *metricsService) ReportBrowserPoolWait(ctx context.Context, pool string, ms float64) error {
+	ctx, err := s.createPoolMeasurementContext(ctx, pool)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.browserPoolWait.M(ms))
+
+	return nil
+}
+
+func (s *metricsService) ReportBrowserRecycle(ctx context.Context, pool string, reason string) error {
+	ctx, err := s.createPoolMeasurementContext(ctx, pool)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	ctx, err = tag.New(ctx, tag.Upsert(keyReason, reason))
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.browserRecycles.M(1))
+
+	return nil
+}
+
+func (s *metricsService) ReportFlowTeardownFailure(ctx context.Context, flowName string) error {
+	ctx, err := s.createFlowMeasurementContext(ctx, flowName)
+	if err != nil {
+		return errors.Wrap(err, "Failed setting tags on context")
+	}
+
+	stats.Record(ctx, s.flowTeardownError.M(1))
+
+	return nil
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+func (s *metricsService) createMeasurementContext(ctx context.Context) (context.Context, error) {
+	return tag.New(ctx,
+		tag.Upsert(keyEnvironment, s.settings.Environment))
+}
+
+func (s *metricsService) createFlowMeasurementContext(ctx context.Context, flowName string) (context.Context, error) {
+	return tag.New(ctx,
+		tag.Upsert(keyFlow, flowName),
+		tag.Upsert(keyEnvironment, s.settings.Environment))
+}
+
+func (s *metricsService) createPoolMeasurementContext(ctx context.Context, pool string) (context.Context, error) {
+	return tag.New(ctx,
+		tag.Upsert(keyPool, pool),
+		tag.Upsert(keyEnvironment, s.settings.Environment))
+}
+
 func (s *metricsService) createStepMeasurementContext(ctx context.Context, flowName string, stepName string) (context.Context, error) { //nolint // line length
 	return tag.New(ctx,
 		tag.Upsert(keyFlow, flowName),
@@ -100,6 +361,22 @@ func (s *metricsService) initMetrics() error {
 	s.testsStepErrors = stats.Int64("tests/errors", "The number of step errors", stats.UnitDimensionless)
 	s.testsStepTimeout = stats.Int64("tests/timeouts", "The number of step timeouts", stats.UnitDimensionless)
 	s.testsStepSuccess = stats.Int64("tests/success", "The number of step successes", stats.UnitDimensionless)
+	s.testsStepPhase = stats.Float64("tests/phase_latency", "The latency in milliseconds per test step phase", stats.UnitMilliseconds)
+	s.certificateExpiry = stats.Float64("tests/certificate_expiry", "The days until the step certificate expires", "d")
+	s.testsStepRetry = stats.Int64("tests/retries", "The number of step retries", stats.UnitDimensionless)
+	s.flowPaused = stats.Int64("flows/paused", "Whether the flow is paused", stats.UnitDimensionless)
+	s.reloadSuccess = stats.Int64("config/reload_success", "The number of successful config reloads", stats.UnitDimensionless)
+	s.flowDuration = stats.Float64("flows/latency", "The latency in milliseconds per flow run", stats.UnitMilliseconds)
+	s.flowRuns = stats.Int64("flows/runs", "The number of flow runs", stats.UnitDimensionless)
+	s.flowLastRun = stats.Float64("flows/last_run", "The unix time of the last flow run", stats.UnitSeconds)
+	s.flowLastSuccess = stats.Float64("flows/last_success", "The unix time of the last successful flow run", stats.UnitSeconds)
+	s.flowProbeSuccess = stats.Int64("flows/probe_success", "Whether the last flow run succeeded", stats.UnitDimensionless)
+	s.flowTeardownError = stats.Int64("flows/teardown_errors", "The number of failed flow teardowns", stats.UnitDimensionless)
+	s.reloadFailure = stats.Int64("config/reload_failure", "The number of failed config reloads", stats.UnitDimensionless)
+	s.browserPoolInUse = stats.Int64("browsers/in_use", "The number of browser tabs in use", stats.UnitDimensionless)
+	s.browserPoolSize = stats.Int64("browsers/capacity", "The number of browser tabs the pool can open", stats.UnitDimensionless)
+	s.browserPoolWait = stats.Float64("browsers/wait", "The time in milliseconds waiting for a browser tab", stats.UnitMilliseconds)
+	s.browserRecycles = stats.Int64("browsers/recycles", "The number of recycled browsers", stats.UnitDimensionless)
 
 	latencyStepView := &view.View{
 		Name:        "step_latency_distribution",
@@ -137,8 +414,169 @@ func (s *metricsService) initMetrics() error {
 		TagKeys:     []tag.Key{keyFlow, keyStep, keyEnvironment},
 	}
 
+	latencyStepPhaseView := &view.View{
+		Name:        "step_phase_latency_distribution",
+		Measure:     s.testsStepPhase,
+		Description: "The distribution of the steps phases latencies, e.g. dns, connect, tls and ttfb of http steps",
+
+		// Latency in buckets:
+		// [>=0ms, >=10ms, >=50ms, >=100ms, >=250ms, >=500ms, >=1s, >=5s, >=10s]
+		//nolint:gomnd //false positive
+		Aggregation: view.Distribution(10, 50, 100, 250, 500, 1000, 5000, 10000),
+		TagKeys:     []tag.Key{keyFlow, keyStep, keyPhase, keyEnvironment},
+	}
+
+	certificateExpiryStepView := &view.View{
+		Name:        "step_certificate_expiry_days",
+		Measure:     s.certificateExpiry,
+		Description: "The days until the certificate checked by the step expires, negative once expired",
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{keyFlow, keyStep, keyEnvironment},
+	}
+
+	retryStepCountView := &view.View{
+		Name:        "step_retry_counter",
+		Measure:     s.testsStepRetry,
+		Description: "The number of steps retries",
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{keyFlow, keyStep, keyEnvironment},
+	}
+
+	pausedFlowView := &view.View{
+		Name:        "flow_paused",
+		Measure:     s.flowPaused,
+		Description: "1 if the flow is paused or in a maintenance window, 0 otherwise",
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{keyFlow, keyEnvironment},
+	}
+
+	latencyFlowView := &view.View{
+		Name:        "flow_latency_distribution",
+		Measure:     s.flowDuration,
+		Description: "The distribution of the flow runs latencies",
+
+		// Latency in buckets:
+		// [>=0ms, >=500ms, >=1s, >=10s, >=30s, >=60s, >=90s, >=120s, >=300s, >=600s]
+		//nolint:gomnd //false positive
+		Aggregation: view.Distribution(500, 1000, 10000, 30000, 60000, 90000, 120000, 300000, 600000),
+		TagKeys:     []tag.Key{keyFlow, keyEnvironment},
+	}
+
+	runsFlowCountView := &view.View{
+		Name:        "flow_runs_counter",
+		Measure:     s.flowRuns,
+		Description: "The number of flow runs by result (success, warning, failure or timeout)",
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{keyFlow, keyResult, keyEnvironment},
+	}
+
+	lastRunFlowView := &view.View{
+		Name:        "flow_last_run_timestamp_seconds",
+		Measure:     s.flowLastRun,
+		Description: "The unix time of the last flow run",
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{keyFlow, keyEnvironment},
+	}
+
+	lastSuccessFlowView := &view.View{
+		Name:        "flow_last_success_timestamp_seconds",
+		Measure:     s.flowLastSuccess,
+		Description: "The unix time of the last successful flow run",
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{keyFlow, keyEnvironment},
+	}
+
+	probeSuccessFlowView := &view.View{
+		Name:        "probe_success",
+		Measure:     s.flowProbeSuccess,
+		Description: "1 if the last flow run succeeded, 0 otherwise",
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{keyFlow, keyEnvironment},
+	}
+
+	teardownErrorFlowCountView := &view.View{
+		Name:        "flow_teardown_errors_counter",
+		Measure:     s.flowTeardownError,
+		Description: "The number of flow runs with a failed teardown step",
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{keyFlow, keyEnvironment},
+	}
+
+	reloadSuccessCountView := &view.View{
+		Name:        "config_reload_success_counter",
+		Measure:     s.reloadSuccess,
+		Description: "The number of successful config reloads",
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{keyEnvironment},
+	}
+
+	reloadFailureCountView := &view.View{
+		Name:        "config_reload_failure_counter",
+		Measure:     s.reloadFailure,
+		Description: "The number of failed config reloads, the previous config keeps running",
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{keyEnvironment},
+	}
+
+	inUseBrowserPoolView := &view.View{
+		Name:        "browser_pool_in_use",
+		Measure:     s.browserPoolInUse,
+		Description: "The number of browser tabs in use",
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{keyPool, keyEnvironment},
+	}
+
+	capacityBrowserPoolView := &view.View{
+		Name:        "browser_pool_capacity",
+		Measure:     s.browserPoolSize,
+		Description: "The number of browser tabs the pool can open, the pool is saturated when all are in use",
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{keyPool, keyEnvironment},
+	}
+
+	waitBrowserPoolView := &view.View{
+		Name:        "browser_pool_wait_distribution",
+		Measure:     s.browserPoolWait,
+		Description: "The distribution of the time flow runs wait for a browser tab",
+
+		// Wait in buckets:
+		// [>=0ms, >=100ms, >=500ms, >=1s, >=10s, >=30s, >=60s]
+		//nolint:gomnd //false positive
+		Aggregation: view.Distribution(100, 500, 1000, 10000, 30000, 60000),
+		TagKeys:     []tag.Key{keyPool, keyEnvironment},
+	}
+
+	recycleBrowserCountView := &view.View{
+		Name:        "browser_recycle_counter",
+		Measure:     s.browserRecycles,
+		Description: "The number of recycled browsers by reason (runs or unhealthy)",
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{keyPool, keyReason, keyEnvironment},
+	}
+
 	// Register the views
-	if err := view.Register(latencyStepView, errorStepCountView, successStepCountView, timeoutStepCountView); err != nil {
+	if err := view.Register(
+		latencyStepView,
+		errorStepCountView,
+		successStepCountView,
+		timeoutStepCountView,
+		retryStepCountView,
+		latencyStepPhaseView,
+		certificateExpiryStepView,
+		pausedFlowView,
+		latencyFlowView,
+		runsFlowCountView,
+		lastRunFlowView,
+		lastSuccessFlowView,
+		probeSuccessFlowView,
+		teardownErrorFlowCountView,
+		reloadSuccessCountView,
+		reloadFailureCountView,
+		inUseBrowserPoolView,
+		capacityBrowserPoolView,
+		waitBrowserPoolView,
+		recycleBrowserCountView,
+	); err != nil {
 		return errors.Wrap(err, "Failed to register views")
 	}
 
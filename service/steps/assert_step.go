@@ -0,0 +1,209 @@
+package steps
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const assertStepType = "assert-step"
+
+const (
+	assertTargetText      = "text"
+	assertTargetAttribute = "attribute"
+	assertTargetCount     = "count"
+	assertTargetTitle     = "title"
+	assertTargetURL       = "url"
+	assertTargetReady     = "ready"
+)
+
+const defaultReadyState = "complete"
+
+type assertStepConf struct {
+	Target    string          `validate:"required,oneof=text attribute count title url ready"`
+	Element   elementSelector `mapstructure:",squash"`
+	Attribute string          `validate:"required_if=Target attribute"`
+
+	// string assertions
+	Equals   *string
+	Contains *string
+	Regex    *string
+
+	// count assertions
+	Count *int
+	Min   *int
+	Max   *int
+
+	regexParsed *regexp.Regexp
+}
+
+// assertStep asserts on the text, attributes or number of elements or on the page title, url and readiness
+type assertStep struct {
+	browserStep
+
+	name string
+	conf assertStepConf
+}
+
+func (s *assertStep) GetType() string {
+	return assertStepType
+}
+
+func (s *assertStep) GetName() string {
+	return s.name
+}
+
+func (s *assertStep) Init(name string, input map[string]interface{}) error {
+	var conf assertStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags, page assertions do not need an element
+	if s.needsElement(conf.Target) {
+		err = validator.New().Struct(conf)
+	} else {
+		err = validator.New().StructExcept(conf, "Element.Selector")
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	switch conf.Target {
+	case assertTargetCount:
+		if conf.Count == nil && conf.Min == nil && conf.Max == nil {
+			return errors.Errorf("failed validating step '%s' configuration: one of count, min or max is required", s.GetType()) //nolint // line length
+		}
+	case assertTargetReady:
+		if conf.Equals == nil && conf.Contains == nil && conf.Regex == nil {
+			readyState := defaultReadyState
+			conf.Equals = &readyState
+		}
+	default:
+		if conf.Equals == nil && conf.Contains == nil && conf.Regex == nil {
+			return errors.Errorf("failed validating step '%s' configuration: one of equals, contains or regex is required", s.GetType()) //nolint // line length
+		}
+	}
+
+	if conf.Regex != nil {
+		conf.regexParsed, err = regexp.Compile(*conf.Regex)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' regex", s.GetType())
+		}
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *assertStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	return runTasks(ctx, s.tasks(runCtx.Logger))
+}
+
+func (s *assertStep) tasks(logger *log.Entry) chromedp.Tasks {
+	logger.Infof("asserting %s", s.conf.Target)
+
+	assertTasks := chromedp.Tasks{}
+	if s.needsElement(s.conf.Target) {
+		assertTasks = append(assertTasks, s.conf.Element.waitTasks()...)
+	}
+
+	if s.conf.Target == assertTargetCount {
+		var nodes []*cdp.Node
+		queryOptions := append(s.conf.Element.queryAllOptions(), chromedp.AtLeast(0))
+
+		return append(assertTasks,
+			chromedp.Nodes(s.conf.Element.Selector, &nodes, queryOptions...),
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				return s.assertCount(len(nodes))
+			}),
+		)
+	}
+
+	var actual string
+
+	return append(assertTasks,
+		s.actualAction(&actual),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			return s.assertString(strings.TrimSpace(actual))
+		}),
+	)
+}
+
+func (s *assertStep) needsElement(target string) bool {
+	switch target {
+	case assertTargetText, assertTargetAttribute, assertTargetCount:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *assertStep) actualAction(actual *string) chromedp.Action {
+	switch s.conf.Target {
+	case assertTargetAttribute:
+		return chromedp.ActionFunc(func(ctx context.Context) error {
+			var ok bool
+			err := chromedp.AttributeValue(s.conf.Element.Selector, s.conf.Attribute, actual, &ok, s.conf.Element.queryOptions()...).Do(ctx) //nolint // line length
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return errors.Errorf("attribute assertion failed: attribute '%s' not found on %s", s.conf.Attribute, s.conf.Element.Selector) //nolint // line length
+			}
+
+			return nil
+		})
+	case assertTargetTitle:
+		return chromedp.Title(actual)
+	case assertTargetURL:
+		return chromedp.Location(actual)
+	case assertTargetReady:
+		return chromedp.Evaluate(`document.readyState`, actual)
+	default:
+		return chromedp.Text(s.conf.Element.Selector, actual, s.conf.Element.queryOptions()...)
+	}
+}
+
+func (s *assertStep) assertString(actual string) error {
+	if s.conf.Equals != nil && actual != *s.conf.Equals {
+		return errors.Errorf("%s assertion failed: expected to equal '%s' got '%s'", s.conf.Target, *s.conf.Equals, actual)
+	}
+
+	if s.conf.Contains != nil && !strings.Contains(actual, *s.conf.Contains) {
+		return errors.Errorf("%s assertion failed: expected to contain '%s' got '%s'", s.conf.Target, *s.conf.Contains, actual)
+	}
+
+	if s.conf.regexParsed != nil && !s.conf.regexParsed.MatchString(actual) {
+		return errors.Errorf("%s assertion failed: expected to match '%s' got '%s'", s.conf.Target, *s.conf.Regex, actual)
+	}
+
+	return nil
+}
+
+func (s *assertStep) assertCount(actual int) error {
+	if s.conf.Count != nil && actual != *s.conf.Count {
+		return errors.Errorf("count assertion failed: expected %d elements got %d", *s.conf.Count, actual)
+	}
+
+	if s.conf.Min != nil && actual < *s.conf.Min {
+		return errors.Errorf("count assertion failed: expected at least %d elements got %d", *s.conf.Min, actual)
+	}
+
+	if s.conf.Max != nil && actual > *s.conf.Max {
+		return errors.Errorf("count assertion failed: expected at most %d elements got %d", *s.conf.Max, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	log "github.com/sirupsen/logrus"
+)
+
+// newTestTab opens a headless browser tab, the test is skipped when no browser is installed
+func newTestTab(t *testing.T) context.Context {
+	t.Helper()
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), chromedp.DefaultExecAllocatorOptions[:]...)
+	tabCtx, tabCancel := chromedp.NewContext(allocCtx)
+	t.Cleanup(func() {
+		tabCancel()
+		allocCancel()
+	})
+
+	if err := chromedp.Run(tabCtx); err != nil {
+		t.Skipf("no browser available: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(tabCtx, 30*time.Second)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+func newTestRunContext() *RunContext {
+	return &RunContext{
+		Logger: log.NewEntry(log.StandardLogger()),
+		Vars:   NewVariables(nil),
+	}
+}
+
+func TestAssertStepCountCSS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><ul><li class="item">a</li><li class="item">b</li><li class="item">c</li></ul></body></html>`)
+	}))
+	defer server.Close()
+
+	ctx := newTestTab(t)
+	if err := chromedp.Run(ctx, chromedp.Navigate(server.URL)); err != nil {
+		t.Fatalf("failed navigating: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		wantErr bool
+	}{
+		{"css count", map[string]interface{}{"target": "count", "selector": "li.item", "count": 3}, false},
+		{"css min", map[string]interface{}{"target": "count", "selector": "li.item", "min": 2}, false},
+		{"css max", map[string]interface{}{"target": "count", "selector": "li.item", "max": 2}, true},
+		{"xpath count", map[string]interface{}{"target": "count", "selector": "//li", "selectorType": "xpath", "count": 3}, false}, //nolint // line length
+		{"no match", map[string]interface{}{"target": "count", "selector": "li.missing", "count": 0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &assertStep{}
+			if err := step.Init(tt.name, tt.input); err != nil {
+				t.Fatalf("failed initializing step: %v", err)
+			}
+
+			_, err := step.Run(ctx, newTestRunContext())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAssertStepTasks(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     map[string]interface{}
+		wantTasks int
+	}{
+		{"text css", map[string]interface{}{"target": "text", "selector": "h1", "equals": "Welcome"}, 2},
+		{"text waits for the element", map[string]interface{}{"target": "text", "selector": "h1", "contains": "Welcome", "waitVisible": true}, 3}, //nolint // line length
+		{"count xpath", map[string]interface{}{"target": "count", "selector": "//li", "selectorType": "xpath", "min": 1, "waitVisible": true}, 3}, //nolint // line length
+		{"title does not wait for an element", map[string]interface{}{"target": "title", "equals": "Home", "waitVisible": true}, 2},               //nolint // line length
+	}
+
+	logger := newTestRunContext().Logger
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &assertStep{}
+			if err := step.Init(tt.name, tt.input); err != nil {
+				t.Fatalf("failed initializing step: %v", err)
+			}
+
+			if got := len(step.tasks(logger)); got != tt.wantTasks {
+				t.Fatalf("expected %d tasks got %d", tt.wantTasks, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package steps
+
+import (
+	"context"
+
+	"github.com/chromedp/chromedp"
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const clickStepType = "click-step"
+
+type clickStepConf struct {
+	Element elementSelector `mapstructure:",squash"`
+	Double  bool
+}
+
+type clickStep struct {
+	browserStep
+
+	name string
+	conf clickStepConf
+}
+
+func (s *clickStep) GetType() string {
+	return clickStepType
+}
+
+func (s *clickStep) GetName() string {
+	return s.name
+}
+
+func (s *clickStep) Init(name string, input map[string]interface{}) error {
+	var conf clickStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *clickStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	return runTasks(ctx, s.tasks(runCtx.Logger))
+}
+
+func (s *clickStep) tasks(logger *log.Entry) chromedp.Tasks {
+	logger.Infof("clicking on %s", s.conf.Element.Selector)
+
+	clickTasks := s.conf.Element.waitTasks()
+
+	if s.conf.Double {
+		clickTasks = append(clickTasks, chromedp.DoubleClick(s.conf.Element.Selector, s.conf.Element.queryOptions()...))
+	} else {
+		clickTasks = append(clickTasks, chromedp.Click(s.conf.Element.Selector, s.conf.Element.queryOptions()...))
+	}
+
+	return clickTasks
+}
@@ -0,0 +1,317 @@
+package steps
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/miekg/dns"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const dnsStepType = "dns-step"
+
+const (
+	dnsProtocolUDP = "udp"
+	dnsProtocolDoH = "doh"
+
+	defaultDNSPort     = "53"
+	dnsMessageMimeType = "application/dns-message"
+	maxDNSMessageSize  = 64 << 10
+)
+
+// dns resolution phase reported as step timing
+const dnsPhaseResolve = "dns"
+
+var dnsRecordTypes = map[string]uint16{
+	"A":     dns.TypeA,
+	"AAAA":  dns.TypeAAAA,
+	"CNAME": dns.TypeCNAME,
+	"MX":    dns.TypeMX,
+	"TXT":   dns.TypeTXT,
+}
+
+type dnsStepConf struct {
+	Host     string `validate:"required"`
+	Type     string `validate:"omitempty,oneof=A AAAA CNAME MX TXT"`
+	Resolver *dnsResolverConf
+	Expect   dnsExpectConf
+}
+
+// dnsResolverConf is the resolver queried instead of the system resolver
+type dnsResolverConf struct {
+	Address  string `validate:"required"` // host[:port] for udp and tcp, the query URL for doh
+	Protocol string `validate:"omitempty,oneof=udp tcp doh"`
+}
+
+// dnsExpectConf are the assertions on the answers, at least one answer is expected by default
+type dnsExpectConf struct {
+	Answers    []string // all must be answered, MX answers are the mail host names
+	MinAnswers int      `validate:"gte=0"`
+}
+
+// dnsStep resolves a record without a browser and asserts on the answers
+type dnsStep struct {
+	name   string
+	conf   dnsStepConf
+	client *http.Client // DoH client
+}
+
+func (s *dnsStep) GetType() string {
+	return dnsStepType
+}
+
+func (s *dnsStep) GetName() string {
+	return s.name
+}
+
+func (s *dnsStep) Init(name string, input map[string]interface{}) error {
+	var conf dnsStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	if conf.Type == "" {
+		conf.Type = "A"
+	}
+
+	if conf.Expect.MinAnswers == 0 && len(conf.Expect.Answers) == 0 {
+		conf.Expect.MinAnswers = 1
+	}
+
+	if resolver := conf.Resolver; resolver != nil {
+		if resolver.Protocol == "" {
+			resolver.Protocol = dnsProtocolUDP
+		}
+
+		if resolver.Protocol == dnsProtocolDoH {
+			resolverURL, err := url.Parse(resolver.Address)
+			if err != nil || (resolverURL.Scheme != "https" && resolverURL.Scheme != "http") {
+				return errors.Errorf("failed validating step '%s' configuration: invalid DoH resolver '%s'", s.GetType(), resolver.Address) //nolint // line length
+			}
+			s.client = &http.Client{}
+		} else if _, _, err := net.SplitHostPort(resolver.Address); err != nil {
+			resolver.Address = net.JoinHostPort(resolver.Address, defaultDNSPort)
+		}
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *dnsStep) NeedsBrowser() bool {
+	return false
+}
+
+func (s *dnsStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	start := time.Now()
+
+	var answers []string
+	var err error
+	if s.conf.Resolver == nil {
+		answers, err = s.systemLookup(ctx)
+	} else {
+		answers, err = s.resolverLookup(ctx)
+	}
+
+	result := &Result{Timings: map[string]float64{
+		dnsPhaseResolve: float64(time.Since(start).Nanoseconds()) / 1e6,
+	}}
+	if err != nil {
+		return result, errors.Wrapf(err, "failed resolving %s %s", s.conf.Type, s.conf.Host)
+	}
+
+	runCtx.Logger.Infof("%s %s resolved to %v in %s", s.conf.Type, s.conf.Host, answers, time.Since(start))
+
+	return result, s.assert(answers)
+}
+
+// systemLookup resolves the record with the system resolver
+func (s *dnsStep) systemLookup(ctx context.Context) ([]string, error) {
+	resolver := net.DefaultResolver
+
+	switch s.conf.Type {
+	case "A", "AAAA":
+		network := "ip4"
+		if s.conf.Type == "AAAA" {
+			network = "ip6"
+		}
+
+		ips, err := resolver.LookupIP(ctx, network, s.conf.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		answers := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+		return answers, nil
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, s.conf.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		// the canonical name of a host without CNAME record is the host itself
+		if strings.EqualFold(normalizeDNSName(cname), normalizeDNSName(s.conf.Host)) {
+			return nil, nil
+		}
+		return []string{normalizeDNSName(cname)}, nil
+	case "MX":
+		records, err := resolver.LookupMX(ctx, s.conf.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		answers := make([]string, 0, len(records))
+		for _, record := range records {
+			answers = append(answers, normalizeDNSName(record.Host))
+		}
+		return answers, nil
+	default:
+		return resolver.LookupTXT(ctx, s.conf.Host)
+	}
+}
+
+// resolverLookup queries the configured resolver
+func (s *dnsStep) resolverLookup(ctx context.Context) ([]string, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(dns.Fqdn(s.conf.Host), dnsRecordTypes[s.conf.Type])
+
+	var response *dns.Msg
+	var err error
+	if s.conf.Resolver.Protocol == dnsProtocolDoH {
+		response, err = s.exchangeDoH(ctx, query)
+	} else {
+		client := &dns.Client{Net: s.conf.Resolver.Protocol}
+		response, _, err = client.ExchangeContext(ctx, query, s.conf.Resolver.Address)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed querying %s", s.conf.Resolver.Address)
+	}
+
+	if response.Rcode != dns.RcodeSuccess {
+		return nil, errors.Errorf("%s answered %s", s.conf.Resolver.Address, dns.RcodeToString[response.Rcode])
+	}
+
+	// the answer may hold the CNAME chain of the requested record
+	var answers []string
+	for _, record := range response.Answer {
+		switch record := record.(type) {
+		case *dns.A:
+			if s.conf.Type == "A" {
+				answers = append(answers, record.A.String())
+			}
+		case *dns.AAAA:
+			if s.conf.Type == "AAAA" {
+				answers = append(answers, record.AAAA.String())
+			}
+		case *dns.CNAME:
+			if s.conf.Type == "CNAME" {
+				answers = append(answers, normalizeDNSName(record.Target))
+			}
+		case *dns.MX:
+			if s.conf.Type == "MX" {
+				answers = append(answers, normalizeDNSName(record.Mx))
+			}
+		case *dns.TXT:
+			if s.conf.Type == "TXT" {
+				answers = append(answers, strings.Join(record.Txt, ""))
+			}
+		}
+	}
+
+	return answers, nil
+}
+
+// exchangeDoH sends the query by DNS over HTTPS (RFC 8484)
+func (s *dnsStep) exchangeDoH(ctx context.Context, query *dns.Msg) (*dns.Msg, error) {
+	// the message id should be 0 for http caching
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed packing query")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.conf.Resolver.Address, bytes.NewReader(packed))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating request")
+	}
+	req.Header.Set("Content-Type", dnsMessageMimeType)
+	req.Header.Set("Accept", dnsMessageMimeType)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("DoH resolver returned status %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxDNSMessageSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading response")
+	}
+
+	response := new(dns.Msg)
+	err = response.Unpack(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed unpacking response")
+	}
+
+	return response, nil
+}
+
+func (s *dnsStep) assert(answers []string) error {
+	if len(answers) < s.conf.Expect.MinAnswers {
+		return errors.Errorf("expected at least %d %s answers for %s got %d", s.conf.Expect.MinAnswers, s.conf.Type, s.conf.Host, len(answers)) //nolint // line length
+	}
+
+	for _, expected := range s.conf.Expect.Answers {
+		if !s.containsAnswer(answers, expected) {
+			return errors.Errorf("expected %s answer '%s' for %s got %v", s.conf.Type, expected, s.conf.Host, answers)
+		}
+	}
+
+	return nil
+}
+
+// containsAnswer compares TXT answers exactly and names case insensitively
+func (s *dnsStep) containsAnswer(answers []string, expected string) bool {
+	for _, answer := range answers {
+		if s.conf.Type == "TXT" && answer == expected {
+			return true
+		}
+
+		if s.conf.Type != "TXT" && strings.EqualFold(answer, normalizeDNSName(expected)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeDNSName removes the trailing dot of fully qualified names
+func normalizeDNSName(name string) string {
+	return strings.TrimSuffix(name, ".")
+}
@@ -0,0 +1,221 @@
+package steps
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// testDNSZone answers the test queries, unknown names are NXDOMAIN
+func testDNSZone(query *dns.Msg) *dns.Msg {
+	response := new(dns.Msg)
+	response.SetReply(query)
+
+	question := query.Question[0]
+	rr := func(record string) {
+		parsed, err := dns.NewRR(record)
+		if err != nil {
+			panic(err)
+		}
+		response.Answer = append(response.Answer, parsed)
+	}
+
+	switch {
+	case question.Name == "a.example.com." && question.Qtype == dns.TypeA:
+		rr("a.example.com. 60 IN A 192.0.2.1")
+		rr("a.example.com. 60 IN A 192.0.2.2")
+	case question.Name == "www.example.com.":
+		rr("www.example.com. 60 IN CNAME a.example.com.")
+		if question.Qtype == dns.TypeA {
+			rr("a.example.com. 60 IN A 192.0.2.1")
+		}
+	case question.Name == "example.com." && question.Qtype == dns.TypeMX:
+		rr("example.com. 60 IN MX 10 mail1.example.com.")
+		rr("example.com. 60 IN MX 20 mail2.example.com.")
+	case question.Name == "example.com." && question.Qtype == dns.TypeTXT:
+		rr(`example.com. 60 IN TXT "v=spf1 " "-all"`)
+		rr(`example.com. 60 IN TXT "site-verification=abc"`)
+	case question.Name == "mixed.example.com.":
+		// a resolver may add records of other types, they should not be counted
+		rr("mixed.example.com. 60 IN MX 10 mail1.example.com.")
+		rr("mixed.example.com. 60 IN MX 20 mail2.example.com.")
+		rr(`mixed.example.com. 60 IN TXT "v=spf1 -all"`)
+	case question.Name == "empty.example.com.":
+	default:
+		response.Rcode = dns.RcodeNameError
+	}
+
+	return response
+}
+
+// newTestDNSServer serves the test zone on udp and tcp and returns their addresses
+func newTestDNSServer(t *testing.T) (string, string) {
+	t.Helper()
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+		_ = w.WriteMsg(testDNSZone(query))
+	})
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening on udp: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening on tcp: %v", err)
+	}
+
+	for _, server := range []*dns.Server{
+		{PacketConn: packetConn, Handler: handler},
+		{Listener: listener, Handler: handler},
+	} {
+		started := make(chan struct{})
+		server.NotifyStartedFunc = func() { close(started) }
+		go func(server *dns.Server) { _ = server.ActivateAndServe() }(server)
+		<-started
+		t.Cleanup(func() { _ = server.Shutdown() })
+	}
+
+	return packetConn.LocalAddr().String(), listener.Addr().String()
+}
+
+// newTestDoHServer serves the test zone by DNS over HTTPS
+func newTestDoHServer(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessageMimeType {
+			http.Error(w, "expected a dns message", http.StatusBadRequest)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		query := new(dns.Msg)
+		if err := query.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		packed, err := testDNSZone(query).Pack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", dnsMessageMimeType)
+		_, _ = w.Write(packed)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL + "/dns-query"
+}
+
+func TestDNSStep(t *testing.T) {
+	udpAddress, tcpAddress := newTestDNSServer(t)
+	dohAddress := newTestDoHServer(t)
+
+	resolvers := []struct {
+		protocol string
+		address  string
+	}{
+		{"udp", udpAddress},
+		{"tcp", tcpAddress},
+		{"doh", dohAddress},
+	}
+
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		wantErr string
+	}{
+		{"A", map[string]interface{}{"host": "a.example.com"}, ""},
+		{"A answers", map[string]interface{}{"host": "a.example.com", "expect": map[string]interface{}{"answers": []interface{}{"192.0.2.2", "192.0.2.1"}}}, ""},                        //nolint // line length
+		{"A missing answer", map[string]interface{}{"host": "a.example.com", "expect": map[string]interface{}{"answers": []interface{}{"192.0.2.3"}}}, "expected A answer '192.0.2.3'"}, //nolint // line length
+		{"A min answers", map[string]interface{}{"host": "a.example.com", "expect": map[string]interface{}{"minAnswers": 2}}, ""},
+		{"A too few answers", map[string]interface{}{"host": "a.example.com", "expect": map[string]interface{}{"minAnswers": 3}}, "expected at least 3 A answers"},                                //nolint // line length
+		{"A through CNAME", map[string]interface{}{"host": "www.example.com", "expect": map[string]interface{}{"answers": []interface{}{"192.0.2.1"}}}, ""},                                       //nolint // line length
+		{"CNAME", map[string]interface{}{"host": "www.example.com", "type": "CNAME", "expect": map[string]interface{}{"answers": []interface{}{"A.example.com."}}}, ""},                           //nolint // line length
+		{"MX", map[string]interface{}{"host": "example.com", "type": "MX", "expect": map[string]interface{}{"answers": []interface{}{"mail1.example.com", "mail2.example.com"}}}, ""},             //nolint // line length
+		{"TXT", map[string]interface{}{"host": "example.com", "type": "TXT", "expect": map[string]interface{}{"answers": []interface{}{"v=spf1 -all"}}}, ""},                                      //nolint // line length
+		{"TXT is case sensitive", map[string]interface{}{"host": "example.com", "type": "TXT", "expect": map[string]interface{}{"answers": []interface{}{"V=SPF1 -all"}}}, "expected TXT answer"}, //nolint // line length
+		{"MX ignores other types", map[string]interface{}{"host": "mixed.example.com", "type": "MX", "expect": map[string]interface{}{"minAnswers": 3}}, "expected at least 3 MX answers"},        //nolint // line length
+		{"TXT ignores other types", map[string]interface{}{"host": "mixed.example.com", "type": "TXT", "expect": map[string]interface{}{"minAnswers": 2}}, "expected at least 2 TXT answers"},     //nolint // line length
+		{"no answer", map[string]interface{}{"host": "empty.example.com"}, "expected at least 1 A answers"},
+		{"NXDOMAIN", map[string]interface{}{"host": "missing.example.com"}, "answered NXDOMAIN"},
+	}
+
+	for _, resolver := range resolvers {
+		for _, tt := range tests {
+			t.Run(resolver.protocol+" "+tt.name, func(t *testing.T) {
+				input := map[string]interface{}{
+					"resolver": map[string]interface{}{"address": resolver.address, "protocol": resolver.protocol},
+				}
+				for key, value := range tt.input {
+					input[key] = value
+				}
+
+				step := &dnsStep{}
+				if err := step.Init(tt.name, input); err != nil {
+					t.Fatalf("failed initializing step: %v", err)
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				result, err := step.Run(ctx, newTestRunContext())
+				switch {
+				case tt.wantErr == "" && err != nil:
+					t.Fatalf("expected no error got %v", err)
+				case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+					t.Fatalf("expected error %q got %v", tt.wantErr, err)
+				}
+
+				if _, ok := result.Timings[dnsPhaseResolve]; !ok {
+					t.Fatal("expected the resolve timing")
+				}
+			})
+		}
+	}
+}
+
+func TestDNSStepInitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+	}{
+		{"no host", map[string]interface{}{}},
+		{"unknown type", map[string]interface{}{"host": "example.com", "type": "SRV"}},
+		{"invalid DoH resolver", map[string]interface{}{"host": "example.com", "resolver": map[string]interface{}{"address": "8.8.8.8", "protocol": "doh"}}}, //nolint // line length
+		{"negative min answers", map[string]interface{}{"host": "example.com", "expect": map[string]interface{}{"minAnswers": -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&dnsStep{}).Init(tt.name, tt.input); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDNSStepResolverDefaultPort(t *testing.T) {
+	step := &dnsStep{}
+	err := step.Init("default port", map[string]interface{}{
+		"host":     "example.com",
+		"resolver": map[string]interface{}{"address": "192.0.2.53"},
+	})
+	if err != nil {
+		t.Fatalf("failed initializing step: %v", err)
+	}
+
+	if step.conf.Resolver.Address != "192.0.2.53:53" || step.conf.Resolver.Protocol != dnsProtocolUDP {
+		t.Fatalf("expected udp on port 53 got %+v", step.conf.Resolver)
+	}
+}
@@ -0,0 +1,182 @@
+package steps
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const extractStepType = "extract-step"
+
+const (
+	extractSourceText      = "text"
+	extractSourceValue     = "value"
+	extractSourceAttribute = "attribute"
+	extractSourceHTML      = "html"
+	extractSourceURL       = "url"
+	extractSourceTitle     = "title"
+	extractSourceJS        = "js"
+)
+
+type extractStepConf struct {
+	Variable   string `validate:"required"`
+	Source     string `validate:"omitempty,oneof=text value attribute html url title js"`
+	Selector   string
+	Attribute  string `validate:"required_if=Source attribute"`
+	Expression string `validate:"required_if=Source js"`
+	Regex      string
+
+	regexParsed *regexp.Regexp
+}
+
+// extractStep saves a value found in the page into the flow variables
+type extractStep struct {
+	browserStep
+
+	name string
+	conf extractStepConf
+}
+
+func (s *extractStep) GetType() string {
+	return extractStepType
+}
+
+func (s *extractStep) GetName() string {
+	return s.name
+}
+
+func (s *extractStep) Init(name string, input map[string]interface{}) error {
+	var conf extractStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	if conf.Source == "" {
+		conf.Source = extractSourceText
+	}
+
+	if conf.Selector == "" && s.needsSelector(conf.Source) {
+		return errors.Errorf("failed validating step '%s' configuration: source '%s' requires a selector", s.GetType(), conf.Source) //nolint // line length
+	}
+
+	if conf.Regex != "" {
+		conf.regexParsed, err = regexp.Compile(conf.Regex)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' regex", s.GetType())
+		}
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *extractStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	return runTasks(ctx, s.tasks(runCtx.Logger, runCtx.Vars))
+}
+
+func (s *extractStep) tasks(logger *log.Entry, vars *Variables) chromedp.Tasks {
+	logger.Infof("extracting %s into variable '%s'", s.conf.Source, s.conf.Variable)
+
+	var value string
+
+	return chromedp.Tasks{
+		s.extractAction(&value),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			extracted, err := s.applyRegex(strings.TrimSpace(value))
+			if err != nil {
+				return err
+			}
+
+			vars.Set(s.conf.Variable, extracted)
+			logger.Infof("variable '%s' was set", s.conf.Variable)
+
+			return nil
+		}),
+	}
+}
+
+func (s *extractStep) needsSelector(source string) bool {
+	switch source {
+	case extractSourceURL, extractSourceTitle, extractSourceJS:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *extractStep) extractAction(value *string) chromedp.Action {
+	switch s.conf.Source {
+	case extractSourceValue:
+		return chromedp.Value(s.conf.Selector, value, chromedp.NodeReady)
+	case extractSourceAttribute:
+		return chromedp.ActionFunc(func(ctx context.Context) error {
+			var ok bool
+			err := chromedp.AttributeValue(s.conf.Selector, s.conf.Attribute, value, &ok, chromedp.NodeReady).Do(ctx)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return errors.Errorf("attribute '%s' not found on '%s'", s.conf.Attribute, s.conf.Selector)
+			}
+
+			return nil
+		})
+	case extractSourceHTML:
+		return chromedp.OuterHTML(s.conf.Selector, value, chromedp.NodeReady)
+	case extractSourceURL:
+		return chromedp.Location(value)
+	case extractSourceTitle:
+		return chromedp.Title(value)
+	case extractSourceJS:
+		return chromedp.ActionFunc(func(ctx context.Context) error {
+			var result interface{}
+			if err := chromedp.Evaluate(s.conf.Expression, &result).Do(ctx); err != nil {
+				return err
+			}
+
+			if result == nil {
+				return errors.Errorf("expression '%s' returned no value", s.conf.Expression)
+			}
+
+			*value = jsonString(result)
+
+			return nil
+		})
+	default:
+		return chromedp.Text(s.conf.Selector, value, chromedp.NodeReady)
+	}
+}
+
+// applyRegex returns the first capture group of the regex if defined, otherwise the whole match
+func (s *extractStep) applyRegex(value string) (string, error) {
+	if s.conf.regexParsed == nil {
+		return value, nil
+	}
+
+	match := s.conf.regexParsed.FindStringSubmatch(value)
+	if match == nil {
+		return "", errors.Errorf("regex '%s' did not match extracted value '%s'", s.conf.Regex, value)
+	}
+
+	if len(match) > 1 {
+		return match[1], nil
+	}
+
+	return match[0], nil
+}
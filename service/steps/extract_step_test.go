@@ -0,0 +1,122 @@
+package steps
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestExtractStepInitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+	}{
+		{"no variable", map[string]interface{}{"selector": "h1"}},
+		{"unknown source", map[string]interface{}{"variable": "v", "source": "cookie"}},
+		{"text without selector", map[string]interface{}{"variable": "v"}},
+		{"attribute without attribute", map[string]interface{}{"variable": "v", "source": "attribute", "selector": "a"}},
+		{"js without expression", map[string]interface{}{"variable": "v", "source": "js"}},
+		{"invalid regex", map[string]interface{}{"variable": "v", "selector": "h1", "regex": "("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&extractStep{}).Init(tt.name, tt.input); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestExtractStepApplyRegex(t *testing.T) {
+	tests := []struct {
+		name    string
+		regex   string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"no regex", "", "Order #42", "Order #42", false},
+		{"whole match", `\d+`, "Order #42", "42", false},
+		{"first group", `#(\d+)`, "Order #42", "42", false},
+		{"no match", `#(\d+)`, "Order", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &extractStep{}
+			err := step.Init(tt.name, map[string]interface{}{"variable": "order", "selector": "h1", "regex": tt.regex})
+			if err != nil {
+				t.Fatalf("failed initializing step: %v", err)
+			}
+
+			got, err := step.applyRegex(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("expected %q error %t got %q %v", tt.want, tt.wantErr, got, err)
+			}
+		})
+	}
+}
+
+func TestExtractStep(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Orders</title></head><body><h1> Order #42 </h1><a id="next" href="/orders/43">next</a><input id="qty" value="3"></body></html>`) //nolint // line length
+	}))
+	defer server.Close()
+
+	ctx := newTestTab(t)
+	if err := chromedp.Run(ctx, chromedp.Navigate(server.URL)); err != nil {
+		t.Fatalf("failed navigating: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{"text", map[string]interface{}{"selector": "h1"}, "Order #42", ""},
+		{"text regex", map[string]interface{}{"selector": "h1", "regex": `#(\d+)`}, "42", ""},
+		{"value", map[string]interface{}{"source": "value", "selector": "#qty"}, "3", ""},
+		{"attribute", map[string]interface{}{"source": "attribute", "selector": "#next", "attribute": "href"}, "/orders/43", ""},                           //nolint // line length
+		{"missing attribute", map[string]interface{}{"source": "attribute", "selector": "#next", "attribute": "title"}, "", "attribute 'title' not found"}, //nolint // line length
+		{"title", map[string]interface{}{"source": "title"}, "Orders", ""},
+		{"js string", map[string]interface{}{"source": "js", "expression": "document.title"}, "Orders", ""},
+		{"js number", map[string]interface{}{"source": "js", "expression": "6 * 7"}, "42", ""},
+		{"js boolean", map[string]interface{}{"source": "js", "expression": "document.querySelectorAll('a').length > 0"}, "true", ""}, //nolint // line length
+		{"js object", map[string]interface{}{"source": "js", "expression": "({id: 42, tags: ['a']})"}, `{"id":42,"tags":["a"]}`, ""},  //nolint // line length
+		{"js null", map[string]interface{}{"source": "js", "expression": "null"}, "", "returned no value"},
+		{"js undefined", map[string]interface{}{"source": "js", "expression": "undefined"}, "", "returned no value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := map[string]interface{}{"variable": "extracted"}
+			for key, value := range tt.input {
+				input[key] = value
+			}
+
+			step := &extractStep{}
+			if err := step.Init(tt.name, input); err != nil {
+				t.Fatalf("failed initializing step: %v", err)
+			}
+
+			runCtx := newTestRunContext()
+			_, err := step.Run(ctx, runCtx)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("expected no error got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("expected error %q got %v", tt.wantErr, err)
+			}
+
+			if got, _ := runCtx.Vars.Get("extracted"); tt.wantErr == "" && got != tt.want {
+				t.Fatalf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
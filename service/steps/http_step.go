@@ -0,0 +1,506 @@
+package steps
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/oliveagle/jsonpath"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const httpStepType = "http-step"
+
+const (
+	defaultHTTPMaxRedirects = 10
+	maxHTTPBodySize         = 10 << 20
+)
+
+// http request phases reported as step timings
+const (
+	httpPhaseDNS     = "dns"
+	httpPhaseConnect = "connect"
+	httpPhaseTLS     = "tls"
+	httpPhaseTTFB    = "ttfb"
+)
+
+type httpStepConf struct {
+	Method          string `validate:"omitempty,oneof=GET HEAD POST PUT PATCH DELETE OPTIONS"`
+	URL             string `validate:"required,url"`
+	Headers         map[string]string
+	Body            string
+	JSON            interface{}
+	Form            map[string]string
+	Auth            *httpAuthConf
+	TLS             *httpTLSConf
+	FollowRedirects *bool
+	MaxRedirects    int `validate:"gte=0"`
+	Expect          httpExpectConf
+
+	bodyParsed []byte
+}
+
+type httpAuthConf struct {
+	Username string `validate:"required_without=Token"`
+	Password string
+	Token    string `validate:"required_without=Username,excluded_with=Username"`
+}
+
+type httpTLSConf struct {
+	InsecureSkipVerify bool
+	ServerName         string
+	CAFile             string
+	CertFile           string `validate:"required_with=KeyFile"`
+	KeyFile            string `validate:"required_with=CertFile"`
+}
+
+// httpExpectConf are the assertions on the response, any 2xx status is expected by default
+type httpExpectConf struct {
+	Status     []int
+	Headers    map[string]string  // header name to regex
+	Body       string             // regex
+	JSONPath   []httpJSONPathConf `validate:"dive"`
+	MaxLatency string
+
+	headersParsed    map[string]*regexp.Regexp
+	bodyParsed       *regexp.Regexp
+	maxLatencyParsed time.Duration
+}
+
+type httpJSONPathConf struct {
+	Path   string `validate:"required"`
+	Equals *string
+	Regex  string
+	Exists *bool
+
+	regexParsed *regexp.Regexp
+}
+
+// httpStep sends an HTTP request without a browser and asserts on the response
+type httpStep struct {
+	name   string
+	conf   httpStepConf
+	client *http.Client
+}
+
+// httpTimings are the durations in milliseconds of the request phases,
+// the trace hooks may be called from the transport goroutines
+type httpTimings struct {
+	lock   sync.Mutex
+	phases map[string]float64
+}
+
+func (s *httpStep) GetType() string {
+	return httpStepType
+}
+
+func (s *httpStep) GetName() string {
+	return s.name
+}
+
+func (s *httpStep) Init(name string, input map[string]interface{}) error {
+	var conf httpStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	if conf.Method == "" {
+		conf.Method = http.MethodGet
+	}
+
+	err = s.parseBody(&conf)
+	if err != nil {
+		return err
+	}
+
+	err = s.parseExpect(&conf.Expect)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.newClient(conf)
+	if err != nil {
+		return err
+	}
+
+	s.name = name
+	s.conf = conf
+	s.client = client
+
+	return nil
+}
+
+func (s *httpStep) parseBody(conf *httpStepConf) error {
+	bodies := 0
+	for _, set := range []bool{conf.Body != "", conf.JSON != nil, len(conf.Form) > 0} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return errors.Errorf("failed validating step '%s' configuration: only one of body, json and form can be set", s.GetType()) //nolint // line length
+	}
+
+	switch {
+	case conf.Body != "":
+		conf.bodyParsed = []byte(conf.Body)
+	case conf.JSON != nil:
+		body, err := json.Marshal(jsonCompatible(conf.JSON))
+		if err != nil {
+			return errors.Wrapf(err, "failed encoding step '%s' json body", s.GetType())
+		}
+		conf.bodyParsed = body
+		setDefaultHeader(conf, "Content-Type", "application/json")
+	case len(conf.Form) > 0:
+		form := url.Values{}
+		for key, value := range conf.Form {
+			form.Set(key, value)
+		}
+		conf.bodyParsed = []byte(form.Encode())
+		setDefaultHeader(conf, "Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	return nil
+}
+
+func setDefaultHeader(conf *httpStepConf, name string, value string) {
+	for header := range conf.Headers {
+		if strings.EqualFold(header, name) {
+			return
+		}
+	}
+
+	if conf.Headers == nil {
+		conf.Headers = map[string]string{}
+	}
+	conf.Headers[name] = value
+}
+
+// jsonCompatible converts the yaml maps with interface keys to maps json can encode
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = jsonCompatible(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = jsonCompatible(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+func (s *httpStep) parseExpect(expect *httpExpectConf) error {
+	var err error
+
+	expect.headersParsed = make(map[string]*regexp.Regexp, len(expect.Headers))
+	for header, pattern := range expect.Headers {
+		expect.headersParsed[header], err = regexp.Compile(pattern)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' header '%s' regex", s.GetType(), header)
+		}
+	}
+
+	if expect.Body != "" {
+		expect.bodyParsed, err = regexp.Compile(expect.Body)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' body regex", s.GetType())
+		}
+	}
+
+	for i := range expect.JSONPath {
+		if expect.JSONPath[i].Regex == "" {
+			continue
+		}
+		expect.JSONPath[i].regexParsed, err = regexp.Compile(expect.JSONPath[i].Regex)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' json path '%s' regex", s.GetType(), expect.JSONPath[i].Path)
+		}
+	}
+
+	if expect.MaxLatency != "" {
+		expect.maxLatencyParsed, err = time.ParseDuration(expect.MaxLatency)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' max latency", s.GetType())
+		}
+	}
+
+	return nil
+}
+
+// newClient creates the step client, connections are not reused so every run measures the full connection setup
+func (s *httpStep) newClient(conf httpStepConf) (*http.Client, error) {
+	tlsConfig, err := s.newTLSConfig(conf.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRedirects := conf.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = defaultHTTPMaxRedirects
+	}
+	followRedirects := conf.FollowRedirects == nil || *conf.FollowRedirects
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !followRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= maxRedirects {
+				return errors.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}, nil
+}
+
+func (s *httpStep) newTLSConfig(conf *httpTLSConf) (*tls.Config, error) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: conf.InsecureSkipVerify, //nolint:gosec // explicitly configured
+		ServerName:         conf.ServerName,
+	}
+
+	if conf.CAFile != "" {
+		caCert, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading step '%s' CA file", s.GetType())
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.Errorf("failed parsing step '%s' CA file '%s'", s.GetType(), conf.CAFile)
+		}
+	}
+
+	if conf.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed loading step '%s' client certificate", s.GetType())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func (s *httpStep) NeedsBrowser() bool {
+	return false
+}
+
+func (s *httpStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	timings := &httpTimings{phases: map[string]float64{}}
+	err := s.request(httptrace.WithClientTrace(ctx, timings.trace()), runCtx.Logger)
+
+	return &Result{Timings: timings.copy()}, err
+}
+
+func (s *httpStep) request(ctx context.Context, logger *log.Entry) error {
+	req, err := http.NewRequestWithContext(ctx, s.conf.Method, s.conf.URL, bytes.NewReader(s.conf.bodyParsed))
+	if err != nil {
+		return errors.Wrap(err, "failed creating request")
+	}
+
+	for name, value := range s.conf.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if auth := s.conf.Auth; auth != nil {
+		if auth.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+auth.Token)
+		} else {
+			req.SetBasicAuth(auth.Username, auth.Password)
+		}
+	}
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed sending %s %s", s.conf.Method, s.conf.URL)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHTTPBodySize))
+	if err != nil {
+		return errors.Wrap(err, "failed reading response body")
+	}
+	latency := time.Since(start)
+
+	logger.Infof("%s %s returned %d in %s", s.conf.Method, s.conf.URL, resp.StatusCode, latency)
+
+	return s.assert(resp, body, latency)
+}
+
+// trace records the request phases durations
+func (t *httpTimings) trace() *httptrace.ClientTrace {
+	var dnsStart, connectStart, tlsStart, requestStart time.Time
+	record := func(phase string, since time.Time) {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+
+		t.phases[phase] = float64(time.Since(since).Nanoseconds()) / 1e6
+	}
+
+	return &httptrace.ClientTrace{
+		GetConn:              func(string) { requestStart = time.Now() },
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { record(httpPhaseDNS, dnsStart) },
+		ConnectStart:         func(string, string) { connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { record(httpPhaseConnect, connectStart) },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(httpPhaseTLS, tlsStart) },
+		GotFirstResponseByte: func() { record(httpPhaseTTFB, requestStart) },
+	}
+}
+
+func (t *httpTimings) copy() map[string]float64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	phases := make(map[string]float64, len(t.phases))
+	for phase, ms := range t.phases {
+		phases[phase] = ms
+	}
+
+	return phases
+}
+
+func (s *httpStep) assert(resp *http.Response, body []byte, latency time.Duration) error {
+	expect := s.conf.Expect
+
+	if len(expect.Status) > 0 {
+		if !containsStatus(expect.Status, resp.StatusCode) {
+			return errors.Errorf("expected status %v got %d", expect.Status, resp.StatusCode)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("expected a 2xx status got %d", resp.StatusCode)
+	}
+
+	for header, pattern := range expect.headersParsed {
+		value := resp.Header.Get(header)
+		if !pattern.MatchString(value) {
+			return errors.Errorf("expected header '%s' to match '%s' got '%s'", header, pattern.String(), value)
+		}
+	}
+
+	if expect.bodyParsed != nil && !expect.bodyParsed.Match(body) {
+		return errors.Errorf("expected body to match '%s'", expect.bodyParsed.String())
+	}
+
+	if len(expect.JSONPath) > 0 {
+		err := assertJSONPaths(expect.JSONPath, body)
+		if err != nil {
+			return err
+		}
+	}
+
+	if expect.maxLatencyParsed > 0 && latency > expect.maxLatencyParsed {
+		return errors.Errorf("expected latency <= %s got %s", expect.maxLatencyParsed, latency)
+	}
+
+	return nil
+}
+
+func containsStatus(statuses []int, status int) bool {
+	for _, expected := range statuses {
+		if expected == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func assertJSONPaths(assertions []httpJSONPathConf, body []byte) error {
+	var doc interface{}
+	err := json.Unmarshal(body, &doc)
+	if err != nil {
+		return errors.Wrap(err, "expected a json body")
+	}
+
+	for _, assertion := range assertions {
+		value, err := jsonpath.JsonPathLookup(doc, assertion.Path)
+		found := err == nil
+
+		if assertion.Exists != nil {
+			if found != *assertion.Exists {
+				return errors.Errorf("expected '%s' exists to be %t got %t", assertion.Path, *assertion.Exists, found)
+			}
+			if !found {
+				continue
+			}
+		}
+
+		if !found {
+			return errors.Wrapf(err, "expected '%s' to exist", assertion.Path)
+		}
+
+		actual := jsonString(value)
+
+		if assertion.Equals != nil && actual != *assertion.Equals {
+			return errors.Errorf("expected '%s' to equal '%s' got '%s'", assertion.Path, *assertion.Equals, actual)
+		}
+
+		if assertion.regexParsed != nil && !assertion.regexParsed.MatchString(actual) {
+			return errors.Errorf("expected '%s' to match '%s' got '%s'", assertion.Path, assertion.Regex, actual)
+		}
+	}
+
+	return nil
+}
+
+// jsonString returns strings as is and any other json value encoded
+func jsonString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(encoded)
+}
@@ -0,0 +1,221 @@
+package steps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestHTTPServer serves the http-step test routes, /echo returns the request it received
+func newTestHTTPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Version", "1.2.3")
+		fmt.Fprint(w, `{"user": {"name": "ada", "id": 7, "admin": true}}`)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "slow")
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"method":        r.Method,
+			"authorization": r.Header.Get("Authorization"),
+			"contentType":   r.Header.Get("Content-Type"),
+			"custom":        r.Header.Get("X-Custom"),
+			"body":          string(body),
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestHTTPStep(t *testing.T) {
+	server := newTestHTTPServer(t)
+
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		wantErr string
+	}{
+		{"2xx by default", map[string]interface{}{"url": server.URL + "/ok"}, ""},
+		{"not 2xx", map[string]interface{}{"url": server.URL + "/missing"}, "expected a 2xx status got 404"},
+		{"status", map[string]interface{}{"url": server.URL + "/missing", "expect": map[string]interface{}{"status": []interface{}{404}}}, ""},                                                                                                                     //nolint // line length
+		{"wrong status", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"status": []interface{}{201, 204}}}, "expected status"},                                                                                                //nolint // line length
+		{"header", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"headers": map[string]interface{}{"X-Version": `^1\.2\.`}}}, ""},                                                                                             //nolint // line length
+		{"wrong header", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"headers": map[string]interface{}{"X-Version": `^2\.`}}}, "expected header 'X-Version'"},                                                               //nolint // line length
+		{"missing header", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"headers": map[string]interface{}{"X-Missing": `.+`}}}, "expected header 'X-Missing'"},                                                               //nolint // line length
+		{"body", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"body": `"name":\s*"ada"`}}, ""},                                                                                                                               //nolint // line length
+		{"wrong body", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"body": `"name":\s*"bob"`}}, "expected body to match"},                                                                                                   //nolint // line length
+		{"json path equals", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"jsonPath": []interface{}{map[string]interface{}{"path": "$.user.id", "equals": "7"}}}}, ""},                                                       //nolint // line length
+		{"json path not equal", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"jsonPath": []interface{}{map[string]interface{}{"path": "$.user.name", "equals": "bob"}}}}, "expected '$.user.name' to equal 'bob' got 'ada'"}, //nolint // line length
+		{"json path regex", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"jsonPath": []interface{}{map[string]interface{}{"path": "$.user.name", "regex": "^a"}}}}, ""},                                                      //nolint // line length
+		{"json path regex mismatch", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"jsonPath": []interface{}{map[string]interface{}{"path": "$.user.admin", "regex": "false"}}}}, "expected '$.user.admin' to match"},         //nolint // line length
+		{"json path exists", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"jsonPath": []interface{}{map[string]interface{}{"path": "$.user.email", "exists": false}}}}, ""},                                                  //nolint // line length
+		{"json path missing", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"jsonPath": []interface{}{map[string]interface{}{"path": "$.user.email"}}}}, "expected '$.user.email' to exist"},                                  //nolint // line length
+		{"json path not json", map[string]interface{}{"url": server.URL + "/slow", "expect": map[string]interface{}{"jsonPath": []interface{}{map[string]interface{}{"path": "$.user"}}}}, "expected a json body"},                                                 //nolint // line length
+		{"max latency", map[string]interface{}{"url": server.URL + "/ok", "expect": map[string]interface{}{"maxLatency": "5s"}}, ""},
+		{"max latency exceeded", map[string]interface{}{"url": server.URL + "/slow", "expect": map[string]interface{}{"maxLatency": "10ms"}}, "expected latency <= 10ms"}, //nolint // line length
+		{"connection refused", map[string]interface{}{"url": "http://127.0.0.1:1/"}, "failed sending GET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runTestHTTPStep(t, tt.input)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("expected no error got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("expected error %q got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHTTPStepRedirects(t *testing.T) {
+	server := newTestHTTPServer(t)
+
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		wantErr string
+	}{
+		{"followed", map[string]interface{}{"url": server.URL + "/redirect", "expect": map[string]interface{}{"body": "ada"}}, ""},
+		{"refused", map[string]interface{}{"url": server.URL + "/redirect", "followRedirects": false, "expect": map[string]interface{}{"status": []interface{}{302}}}, ""}, //nolint // line length
+		{"refused is not 2xx", map[string]interface{}{"url": server.URL + "/redirect", "followRedirects": false}, "expected a 2xx status got 302"},                         //nolint // line length
+		{"too many", map[string]interface{}{"url": server.URL + "/loop", "maxRedirects": 3}, "stopped after 3 redirects"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runTestHTTPStep(t, tt.input)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("expected no error got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("expected error %q got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHTTPStepRequest(t *testing.T) {
+	server := newTestHTTPServer(t)
+
+	tests := []struct {
+		name   string
+		input  map[string]interface{}
+		expect map[string]string
+	}{
+		{
+			name:   "basic auth",
+			input:  map[string]interface{}{"auth": map[string]interface{}{"username": "ada", "password": "secret"}},
+			expect: map[string]string{"method": "GET", "authorization": "Basic YWRhOnNlY3JldA=="},
+		},
+		{
+			name:   "bearer auth",
+			input:  map[string]interface{}{"auth": map[string]interface{}{"token": "abc"}},
+			expect: map[string]string{"authorization": "Bearer abc"},
+		},
+		{
+			name:   "headers and body",
+			input:  map[string]interface{}{"method": "PUT", "headers": map[string]interface{}{"X-Custom": "value"}, "body": "raw"},
+			expect: map[string]string{"method": "PUT", "custom": "value", "body": "raw"},
+		},
+		{
+			name:   "json",
+			input:  map[string]interface{}{"method": "POST", "json": map[string]interface{}{"name": "ada"}},
+			expect: map[string]string{"contentType": "application/json", "body": `{"name":"ada"}`},
+		},
+		{
+			name:   "json keeps the content type header",
+			input:  map[string]interface{}{"method": "POST", "headers": map[string]interface{}{"content-type": "application/vnd+json"}, "json": []interface{}{1}}, //nolint // line length
+			expect: map[string]string{"contentType": "application/vnd+json", "body": `[1]`},
+		},
+		{
+			name:   "form",
+			input:  map[string]interface{}{"method": "POST", "form": map[string]interface{}{"name": "ada lovelace"}},
+			expect: map[string]string{"contentType": "application/x-www-form-urlencoded", "body": "name=ada+lovelace"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := map[string]interface{}{"url": server.URL + "/echo"}
+			for key, value := range tt.input {
+				input[key] = value
+			}
+
+			var assertions []interface{}
+			for field, value := range tt.expect {
+				assertions = append(assertions, map[string]interface{}{"path": "$." + field, "equals": value})
+			}
+			input["expect"] = map[string]interface{}{"jsonPath": assertions}
+
+			if err := runTestHTTPStep(t, input); err != nil {
+				t.Fatalf("expected no error got %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPStepInitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+	}{
+		{"no url", map[string]interface{}{}},
+		{"unknown method", map[string]interface{}{"url": "http://example.com", "method": "FETCH"}},
+		{"body and json", map[string]interface{}{"url": "http://example.com", "body": "a", "json": map[string]interface{}{"a": 1}}},
+		{"username and token", map[string]interface{}{"url": "http://example.com", "auth": map[string]interface{}{"username": "a", "token": "b"}}}, //nolint // line length
+		{"invalid body regex", map[string]interface{}{"url": "http://example.com", "expect": map[string]interface{}{"body": "("}}},
+		{"invalid max latency", map[string]interface{}{"url": "http://example.com", "expect": map[string]interface{}{"maxLatency": "fast"}}}, //nolint // line length
+		{"missing CA file", map[string]interface{}{"url": "https://example.com", "tls": map[string]interface{}{"caFile": "/missing.pem"}}},   //nolint // line length
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&httpStep{}).Init(tt.name, tt.input); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func runTestHTTPStep(t *testing.T, input map[string]interface{}) error {
+	t.Helper()
+
+	step := &httpStep{}
+	if err := step.Init("http", input); err != nil {
+		t.Fatalf("failed initializing step: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := step.Run(ctx, newTestRunContext())
+	return err
+}
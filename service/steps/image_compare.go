@@ -0,0 +1,303 @@
+package steps
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	hashModeAverage    = "ahash"
+	hashModeDifference = "dhash"
+	hashModePerceptual = "phash"
+
+	hashSize        = 8
+	phashSampleSize = 32
+)
+
+// imageRegion is a rectangle of the screenshot, in pixels
+type imageRegion struct {
+	X      int
+	Y      int
+	Width  int `validate:"gt=0"`
+	Height int `validate:"gt=0"`
+}
+
+func (r imageRegion) contains(x int, y int) bool {
+	return x >= r.X && x < r.X+r.Width && y >= r.Y && y < r.Y+r.Height
+}
+
+// pixelDiffResult is the outcome of comparing an image to its baseline pixel by pixel
+type pixelDiffResult struct {
+	differentPixels int
+	comparedPixels  int
+	diffImage       *image.RGBA
+}
+
+func (r pixelDiffResult) differencePercentage() float64 {
+	if r.comparedPixels == 0 {
+		return 0
+	}
+
+	return float64(r.differentPixels) * 100 / float64(r.comparedPixels)
+}
+
+// imageHash calculates the 64 bit perceptual hash of the image using the given hash mode
+func imageHash(img image.Image, mode string) (uint64, error) {
+	switch mode {
+	case hashModeAverage:
+		return averageHash(img), nil
+	case hashModeDifference:
+		return differenceHash(img), nil
+	case hashModePerceptual:
+		return perceptualHash(img), nil
+	default:
+		return 0, errors.Errorf("unknown hash mode '%s'", mode)
+	}
+}
+
+func parseImageHash(hash string) (uint64, error) {
+	return strconv.ParseUint(hash, 16, 64)
+}
+
+func formatImageHash(hash uint64) string {
+	return strconv.FormatUint(hash, 16)
+}
+
+func hammingDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// averageHash sets a bit for every pixel of the 8x8 grayscale image brighter than the mean
+func averageHash(img image.Image) uint64 {
+	pixels := grayscaleResize(img, hashSize, hashSize)
+
+	var sum float64
+	for _, p := range pixels {
+		sum += p
+	}
+	mean := sum / float64(len(pixels))
+
+	var hash uint64
+	for i, p := range pixels {
+		if p > mean {
+			hash |= 1 << uint(i)
+		}
+	}
+
+	return hash
+}
+
+// differenceHash sets a bit for every pixel of the 9x8 grayscale image brighter than its right neighbour
+func differenceHash(img image.Image) uint64 {
+	pixels := grayscaleResize(img, hashSize+1, hashSize)
+
+	var hash uint64
+	for y := 0; y < hashSize; y++ {
+		for x := 0; x < hashSize; x++ {
+			left := pixels[y*(hashSize+1)+x]
+			right := pixels[y*(hashSize+1)+x+1]
+			if left > right {
+				hash |= 1 << uint(y*hashSize+x)
+			}
+		}
+	}
+
+	return hash
+}
+
+// perceptualHash sets a bit for every low frequency DCT coefficient of the 32x32 grayscale image above the median
+func perceptualHash(img image.Image) uint64 {
+	pixels := grayscaleResize(img, phashSampleSize, phashSampleSize)
+	coefficients := dct2D(pixels, phashSampleSize)
+
+	lowFrequencies := make([]float64, 0, hashSize*hashSize)
+	for y := 0; y < hashSize; y++ {
+		for x := 0; x < hashSize; x++ {
+			lowFrequencies = append(lowFrequencies, coefficients[y*phashSampleSize+x])
+		}
+	}
+
+	// the DC coefficient is excluded from the median as it dominates the other values
+	sorted := append([]float64{}, lowFrequencies[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for i, c := range lowFrequencies {
+		if c > median {
+			hash |= 1 << uint(i)
+		}
+	}
+
+	return hash
+}
+
+// dct2D calculates the type II discrete cosine transform of a size x size matrix
+func dct2D(pixels []float64, size int) []float64 {
+	rows := make([]float64, size*size)
+	for y := 0; y < size; y++ {
+		copy(rows[y*size:(y+1)*size], dct1D(pixels[y*size:(y+1)*size]))
+	}
+
+	result := make([]float64, size*size)
+	column := make([]float64, size)
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			column[y] = rows[y*size+x]
+		}
+
+		transformed := dct1D(column)
+		for y := 0; y < size; y++ {
+			result[y*size+x] = transformed[y]
+		}
+	}
+
+	return result
+}
+
+func dct1D(values []float64) []float64 {
+	n := len(values)
+	result := make([]float64, n)
+
+	for k := 0; k < n; k++ {
+		var sum float64
+		for i, v := range values {
+			sum += v * math.Cos(math.Pi/float64(n)*(float64(i)+0.5)*float64(k))
+		}
+		result[k] = sum
+	}
+
+	return result
+}
+
+// grayscaleResize averages the image luminance into a width x height grid
+func grayscaleResize(img image.Image, width int, height int) []float64 {
+	bounds := img.Bounds()
+	sums := make([]float64, width*height)
+	counts := make([]float64, width*height)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		cellY := (y - bounds.Min.Y) * height / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cellX := (x - bounds.Min.X) * width / bounds.Dx()
+			gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+
+			sums[cellY*width+cellX] += float64(gray.Y)
+			counts[cellY*width+cellX]++
+		}
+	}
+
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= counts[i]
+		}
+	}
+
+	return sums
+}
+
+// pixelDiff compares the images pixel by pixel, pixels inside the ignored regions are skipped
+// and pixels with a channel difference above colorThreshold are marked red in the diff image
+func pixelDiff(baseline image.Image, actual image.Image, colorThreshold uint8, ignoreRegions []imageRegion) (pixelDiffResult, error) { //nolint // line length
+	result := pixelDiffResult{}
+
+	if baseline.Bounds().Size() != actual.Bounds().Size() {
+		return result, errors.Errorf("image size %v is different than baseline size %v",
+			actual.Bounds().Size(), baseline.Bounds().Size())
+	}
+
+	size := actual.Bounds().Size()
+	result.diffImage = image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	draw.Draw(result.diffImage, result.diffImage.Bounds(), actual, actual.Bounds().Min, draw.Src)
+
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if isIgnored(x, y, ignoreRegions) {
+				continue
+			}
+			result.comparedPixels++
+
+			baselinePixel := baseline.At(baseline.Bounds().Min.X+x, baseline.Bounds().Min.Y+y)
+			actualPixel := actual.At(actual.Bounds().Min.X+x, actual.Bounds().Min.Y+y)
+			if colorDistance(baselinePixel, actualPixel) > colorThreshold {
+				result.differentPixels++
+				result.diffImage.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				// fade the matching pixels so the differences stand out
+				gray := color.GrayModel.Convert(actualPixel).(color.Gray)
+				faded := 128 + gray.Y/2
+				result.diffImage.Set(x, y, color.RGBA{R: faded, G: faded, B: faded, A: 255})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func isIgnored(x int, y int, ignoreRegions []imageRegion) bool {
+	for _, region := range ignoreRegions {
+		if region.contains(x, y) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// colorDistance returns the biggest 8 bit channel difference between the colors
+func colorDistance(a color.Color, b color.Color) uint8 {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+
+	var max uint32
+	for _, d := range []uint32{absDiff(r1, r2), absDiff(g1, g2), absDiff(b1, b2), absDiff(a1, a2)} {
+		if d > max {
+			max = d
+		}
+	}
+
+	return uint8(max >> 8)
+}
+
+func absDiff(a uint32, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+
+	return b - a
+}
+
+func readPNG(filePath string) (image.Image, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed opening image '%s'", filePath)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decoding image '%s'", filePath)
+	}
+
+	return img, nil
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed encoding image")
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,232 @@
+package steps
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"strings"
+	"testing"
+)
+
+// newTestImage draws a grid of gray blocks with a fixed pattern so the hashes have some structure,
+// the content only depends on the relative position so scaled images look the same
+func newTestImage(width int, height int) *image.RGBA {
+	const cells = 8
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			cellX, cellY := x*cells/width, y*cells/height
+			value := uint8((cellX*37 + cellY*91 + cellX*cellY*13) % 256)
+			img.Set(x, y, color.RGBA{R: value, G: value, B: value, A: 255})
+		}
+	}
+
+	return img
+}
+
+// withRect returns a copy of the image with the rectangle filled with the color
+func withRect(img *image.RGBA, rect image.Rectangle, c color.Color) *image.RGBA {
+	changed := image.NewRGBA(img.Bounds())
+	copy(changed.Pix, img.Pix)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			changed.Set(x, y, c)
+		}
+	}
+
+	return changed
+}
+
+// inverted returns a copy of the image with inverted colors
+func inverted(img *image.RGBA) *image.RGBA {
+	changed := image.NewRGBA(img.Bounds())
+	for i, value := range img.Pix {
+		if i%4 == 3 {
+			changed.Pix[i] = value
+			continue
+		}
+		changed.Pix[i] = 255 - value
+	}
+
+	return changed
+}
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		a    uint64
+		b    uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0xff, 0xff, 0},
+		{0, 1, 1},
+		{0xf0, 0x0f, 8},
+		{0, ^uint64(0), 64},
+	}
+
+	for _, tt := range tests {
+		if got := hammingDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("%x %x: expected %d got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func TestImageHash(t *testing.T) {
+	baseline := newTestImage(64, 64)
+	smallChange := withRect(baseline, image.Rect(10, 10, 12, 12), color.Black)
+	largeChange := inverted(baseline)
+
+	const threshold = 4
+
+	for _, mode := range []string{hashModeAverage, hashModeDifference, hashModePerceptual} {
+		t.Run(mode, func(t *testing.T) {
+			baselineHash, err := imageHash(baseline, mode)
+			if err != nil {
+				t.Fatalf("failed hashing: %v", err)
+			}
+
+			identicalHash, _ := imageHash(newTestImage(64, 64), mode)
+			if distance := hammingDistance(baselineHash, identicalHash); distance != 0 {
+				t.Errorf("expected identical images to have the same hash, distance %d", distance)
+			}
+
+			smallHash, _ := imageHash(smallChange, mode)
+			if distance := hammingDistance(baselineHash, smallHash); distance > threshold {
+				t.Errorf("expected a small change within the threshold, distance %d", distance)
+			}
+
+			largeHash, _ := imageHash(largeChange, mode)
+			if distance := hammingDistance(baselineHash, largeHash); distance <= threshold*4 {
+				t.Errorf("expected a large change above the threshold, distance %d", distance)
+			}
+
+			// the hash only depends on the image content, not its resolution
+			scaledHash, _ := imageHash(newTestImage(128, 128), mode)
+			if distance := hammingDistance(baselineHash, scaledHash); distance > threshold {
+				t.Errorf("expected a scaled image within the threshold, distance %d", distance)
+			}
+
+			parsed, err := parseImageHash(formatImageHash(baselineHash))
+			if err != nil || parsed != baselineHash {
+				t.Errorf("expected the formatted hash to parse back, got %x %v", parsed, err)
+			}
+		})
+	}
+
+	if _, err := imageHash(baseline, "md5"); err == nil {
+		t.Fatal("expected an error for an unknown hash mode")
+	}
+}
+
+func TestPixelDiff(t *testing.T) {
+	baseline := newTestImage(64, 64)
+	changedRect := image.Rect(8, 8, 16, 16)
+
+	tests := []struct {
+		name           string
+		actual         *image.RGBA
+		colorThreshold uint8
+		ignoreRegions  []imageRegion
+		wantDifferent  int
+		wantCompared   int
+	}{
+		{"identical", newTestImage(64, 64), 0, nil, 0, 64 * 64},
+		{"small change", withRect(baseline, image.Rect(0, 0, 2, 2), color.White), 0, nil, 4, 64 * 64},
+		{"large change", inverted(baseline), 0, nil, 64 * 64, 64 * 64},
+		{"ignored region", withRect(baseline, changedRect, color.Black), 0, []imageRegion{{X: 8, Y: 8, Width: 8, Height: 8}}, 0, 64*64 - 64},         //nolint // line length
+		{"partly ignored region", withRect(baseline, changedRect, color.Black), 0, []imageRegion{{X: 8, Y: 8, Width: 4, Height: 8}}, 32, 64*64 - 32}, //nolint // line length
+		{"below color threshold", brightened(baseline, 3), 5, nil, 0, 64 * 64},
+		{"above color threshold", brightened(baseline, 10), 5, nil, 64 * 64, 64 * 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := pixelDiff(baseline, tt.actual, tt.colorThreshold, tt.ignoreRegions)
+			if err != nil {
+				t.Fatalf("failed comparing: %v", err)
+			}
+
+			if result.differentPixels != tt.wantDifferent || result.comparedPixels != tt.wantCompared {
+				t.Fatalf("expected %d/%d different pixels got %d/%d",
+					tt.wantDifferent, tt.wantCompared, result.differentPixels, result.comparedPixels)
+			}
+
+			if result.diffImage.Bounds() != baseline.Bounds() {
+				t.Fatalf("expected a diff image of the baseline size got %v", result.diffImage.Bounds())
+			}
+		})
+	}
+
+	_, err := pixelDiff(baseline, newTestImage(64, 32), 0, nil)
+	if err == nil || !strings.Contains(err.Error(), "different than baseline size") {
+		t.Fatalf("expected a size mismatch error got %v", err)
+	}
+}
+
+// brightened returns a copy of the image with every color channel shifted by delta,
+// lowered instead for the channels which would overflow
+func brightened(img *image.RGBA, delta uint8) *image.RGBA {
+	changed := image.NewRGBA(img.Bounds())
+	for i, value := range img.Pix {
+		switch {
+		case i%4 == 3:
+			changed.Pix[i] = value
+		case value > 255-delta:
+			changed.Pix[i] = value - delta
+		default:
+			changed.Pix[i] = value + delta
+		}
+	}
+
+	return changed
+}
+
+func TestValidateScreenshotPixels(t *testing.T) {
+	baseline := newTestImage(64, 64)
+	diffFolder := t.TempDir()
+
+	tests := []struct {
+		name    string
+		actual  *image.RGBA
+		conf    validateStepConf
+		wantErr string
+	}{
+		{"identical", newTestImage(64, 64), validateStepConf{}, ""},
+		{"within tolerance", withRect(baseline, image.Rect(0, 0, 2, 2), color.White), validateStepConf{Tolerance: 0.5}, ""},
+		{"above tolerance", withRect(baseline, image.Rect(0, 0, 16, 16), color.White), validateStepConf{Tolerance: 0.5}, "6.250% of the pixels are different (256/4096)"}, //nolint // line length
+		{"ignored region", withRect(baseline, image.Rect(0, 0, 16, 16), color.White), validateStepConf{IgnoreRegions: []imageRegion{{Width: 16, Height: 16}}}, ""},        //nolint // line length
+		{"size mismatch", newTestImage(32, 32), validateStepConf{}, "different than baseline size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := encodePNG(tt.actual)
+			if err != nil {
+				t.Fatalf("failed encoding: %v", err)
+			}
+
+			step := &validateStep{name: tt.name, conf: tt.conf}
+			step.conf.Mode = validateModePixel
+			step.conf.baselineImage = baseline
+			step.conf.DiffFolder = diffFolder
+
+			err = step.validateScreenshot(newTestRunContext().Logger, nil, buf)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("expected no error got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("expected error %q got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	files, err := os.ReadDir(diffFolder)
+	if err != nil {
+		t.Fatalf("failed reading diff folder: %v", err)
+	}
+	// the actual screenshots of both failures and the diff image of the tolerance failure
+	if len(files) != 3 {
+		t.Fatalf("expected 3 images in the diff folder got %d", len(files))
+	}
+}
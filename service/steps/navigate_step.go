@@ -1,9 +1,11 @@
 package steps
 
 import (
+	"context"
+
 	"github.com/chromedp/chromedp"
 	"github.com/go-playground/validator/v10"
-	"github.com/mitchellh/mapstructure"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -11,10 +13,12 @@ import (
 const navigateStepType = "navigate-step"
 
 type navigateStepConf struct {
-	URL string `validate:"required"`
+	URL string `validate:"required,url"`
 }
 
 type navigateStep struct {
+	browserStep
+
 	name string
 	conf navigateStepConf
 }
@@ -29,7 +33,7 @@ func (s *navigateStep) GetName() string {
 
 func (s *navigateStep) Init(name string, input map[string]interface{}) error {
 	var conf navigateStepConf
-	err := mapstructure.Decode(input, &conf)
+	err := config.Decode(input, &conf)
 	if err != nil {
 		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
 	}
@@ -46,7 +50,11 @@ func (s *navigateStep) Init(name string, input map[string]interface{}) error {
 	return nil
 }
 
-func (s *navigateStep) Run(logger *log.Entry) chromedp.Tasks {
+func (s *navigateStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	return runTasks(ctx, s.tasks(runCtx.Logger))
+}
+
+func (s *navigateStep) tasks(logger *log.Entry) chromedp.Tasks {
 	logger.Infof("navigating to %s", s.conf.URL)
 
 	return chromedp.Tasks{
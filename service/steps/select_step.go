@@ -0,0 +1,105 @@
+package steps
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const selectStepType = "select-step"
+
+// selectOptionFunction selects the option matching the value or text and fires the change events
+const selectOptionFunction = `function(byText, expected) {
+	const option = [...this.options].find((o) => byText ? o.text.trim() === expected : o.value === expected)
+	if (!option) {
+		return false
+	}
+	this.value = option.value
+	this.dispatchEvent(new Event('input', { bubbles: true }))
+	this.dispatchEvent(new Event('change', { bubbles: true }))
+	return true
+}`
+
+type selectStepConf struct {
+	Element elementSelector `mapstructure:",squash"`
+	Value   string          `validate:"required_without=Text"`
+	Text    string          `validate:"required_without=Value"`
+}
+
+// selectStep selects an option of a select element by its value or visible text
+type selectStep struct {
+	browserStep
+
+	name string
+	conf selectStepConf
+}
+
+func (s *selectStep) GetType() string {
+	return selectStepType
+}
+
+func (s *selectStep) GetName() string {
+	return s.name
+}
+
+func (s *selectStep) Init(name string, input map[string]interface{}) error {
+	var conf selectStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *selectStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	return runTasks(ctx, s.tasks(runCtx.Logger))
+}
+
+func (s *selectStep) tasks(logger *log.Entry) chromedp.Tasks {
+	byText := s.conf.Value == ""
+	expected := s.conf.Value
+	if byText {
+		expected = s.conf.Text
+	}
+
+	logger.Infof("selecting option '%s' of %s", expected, s.conf.Element.Selector)
+
+	return append(s.conf.Element.waitTasks(),
+		chromedp.QueryAfter(s.conf.Element.Selector,
+			func(ctx context.Context, _ runtime.ExecutionContextID, nodes ...*cdp.Node) error {
+				if len(nodes) == 0 {
+					return errors.Errorf("no element matches %s", s.conf.Element.Selector)
+				}
+
+				var selected bool
+				err := callNodeFunction(ctx, nodes[0], selectOptionFunction, &selected, byText, expected)
+				if err != nil {
+					return errors.Wrap(err, "failed selecting option")
+				}
+
+				if !selected {
+					return errors.Errorf("option '%s' not found in %s", expected, s.conf.Element.Selector)
+				}
+
+				return nil
+			},
+			s.conf.Element.queryOptions()...,
+		),
+	)
+}
@@ -0,0 +1,84 @@
+package steps
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/pkg/errors"
+)
+
+const (
+	selectorTypeCSS    = "css"
+	selectorTypeXPath  = "xpath"
+	selectorTypeJSPath = "jspath"
+)
+
+// elementSelector is the shared configuration of steps interacting with a page element,
+// it is squashed into the step configuration so its keys are declared next to the step keys
+type elementSelector struct {
+	Selector     string `validate:"required"`
+	SelectorType string `validate:"omitempty,oneof=css xpath jspath"`
+	WaitVisible  bool
+	WaitEnabled  bool
+}
+
+// queryOptions returns the chromedp query options matching the selector type
+func (e *elementSelector) queryOptions() []chromedp.QueryOption {
+	var by chromedp.QueryOption
+
+	switch e.SelectorType {
+	case selectorTypeXPath:
+		by = chromedp.BySearch
+	case selectorTypeJSPath:
+		by = chromedp.ByJSPath
+	default:
+		by = chromedp.ByQuery
+	}
+
+	return []chromedp.QueryOption{by, chromedp.NodeReady}
+}
+
+// queryAllOptions returns the chromedp query options matching all the elements of the selector,
+// ByQuery only returns the first element matching a css selector
+func (e *elementSelector) queryAllOptions() []chromedp.QueryOption {
+	options := e.queryOptions()
+	if e.SelectorType != selectorTypeXPath && e.SelectorType != selectorTypeJSPath {
+		options[0] = chromedp.ByQueryAll
+	}
+
+	return options
+}
+
+// waitTasks returns the tasks waiting for the element to become visible and/or enabled
+func (e *elementSelector) waitTasks() chromedp.Tasks {
+	waitTasks := chromedp.Tasks{}
+	by := e.queryOptions()[0]
+
+	if e.WaitVisible {
+		waitTasks = append(waitTasks, chromedp.WaitVisible(e.Selector, by))
+	}
+
+	if e.WaitEnabled {
+		waitTasks = append(waitTasks, chromedp.WaitEnabled(e.Selector, by))
+	}
+
+	return waitTasks
+}
+
+// callNodeFunction calls the javascript function with the node as 'this'
+func callNodeFunction(ctx context.Context, node *cdp.Node, function string, res interface{}, args ...interface{}) error { //nolint // line length
+	obj, err := dom.ResolveNode().WithNodeID(node.NodeID).Do(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed resolving node")
+	}
+	defer func() {
+		_ = runtime.ReleaseObject(obj.ObjectID).Do(ctx)
+	}()
+
+	return chromedp.CallFunctionOn(function, res, func(p *runtime.CallFunctionOnParams) *runtime.CallFunctionOnParams {
+		return p.WithObjectID(obj.ObjectID)
+	}, args...).Do(ctx)
+}
@@ -0,0 +1,126 @@
+package steps
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/chromedp"
+)
+
+// sameQueryOption compares the query options by their function
+func sameQueryOption(a chromedp.QueryOption, b chromedp.QueryOption) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func TestElementSelectorQueryOptions(t *testing.T) {
+	tests := []struct {
+		selectorType string
+		wantBy       chromedp.QueryOption
+		wantAllBy    chromedp.QueryOption
+	}{
+		{"", chromedp.ByQuery, chromedp.ByQueryAll},
+		{selectorTypeCSS, chromedp.ByQuery, chromedp.ByQueryAll},
+		{selectorTypeXPath, chromedp.BySearch, chromedp.BySearch},
+		{selectorTypeJSPath, chromedp.ByJSPath, chromedp.ByJSPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selectorType, func(t *testing.T) {
+			selector := &elementSelector{Selector: "#login", SelectorType: tt.selectorType}
+
+			options := selector.queryOptions()
+			if len(options) != 2 || !sameQueryOption(options[0], tt.wantBy) || !sameQueryOption(options[1], chromedp.NodeReady) {
+				t.Fatalf("unexpected query options %v", options)
+			}
+
+			allOptions := selector.queryAllOptions()
+			if len(allOptions) != 2 || !sameQueryOption(allOptions[0], tt.wantAllBy) {
+				t.Fatalf("unexpected query all options %v", allOptions)
+			}
+		})
+	}
+}
+
+func TestElementSelectorWaitTasks(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector elementSelector
+		want     int
+	}{
+		{"no wait", elementSelector{Selector: "#login"}, 0},
+		{"visible", elementSelector{Selector: "#login", WaitVisible: true}, 1},
+		{"visible and enabled", elementSelector{Selector: "//button", SelectorType: selectorTypeXPath, WaitVisible: true, WaitEnabled: true}, 2}, //nolint // line length
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.selector.waitTasks()); got != tt.want {
+				t.Fatalf("expected %d wait tasks got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestElementStepsTasks(t *testing.T) {
+	tests := []struct {
+		name      string
+		step      StepInterface
+		input     map[string]interface{}
+		wantTasks int
+	}{
+		{"click css", &clickStep{}, map[string]interface{}{"selector": "#login"}, 1},
+		{"double click xpath", &clickStep{}, map[string]interface{}{"selector": "//button", "selectorType": "xpath", "double": true, "waitVisible": true}, 2}, //nolint // line length
+		{"type", &typeStep{}, map[string]interface{}{"selector": "#user", "text": "admin"}, 1},
+		{"type clear and submit", &typeStep{}, map[string]interface{}{"selector": "#user", "text": "admin", "clear": true, "submit": true, "waitEnabled": true}, 4}, //nolint // line length
+		{"select by value", &selectStep{}, map[string]interface{}{"selector": "#country", "value": "fr"}, 1},
+		{"select by text", &selectStep{}, map[string]interface{}{"selector": "#country", "text": "France", "waitVisible": true}, 2}, //nolint // line length
+		{"submit jspath", &submitStep{}, map[string]interface{}{"selector": "document.forms[0]", "selectorType": "jspath"}, 1},
+	}
+
+	logger := newTestRunContext().Logger
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.step.Init(tt.name, tt.input); err != nil {
+				t.Fatalf("failed initializing step: %v", err)
+			}
+
+			var tasks []chromedp.Action
+			switch step := tt.step.(type) {
+			case *clickStep:
+				tasks = step.tasks(logger)
+			case *typeStep:
+				tasks = step.tasks(logger)
+			case *selectStep:
+				tasks = step.tasks(logger)
+			case *submitStep:
+				tasks = step.tasks(logger)
+			}
+
+			if len(tasks) != tt.wantTasks {
+				t.Fatalf("expected %d tasks got %d", tt.wantTasks, len(tasks))
+			}
+		})
+	}
+}
+
+func TestElementStepsInitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		step  StepInterface
+		input map[string]interface{}
+	}{
+		{"click without selector", &clickStep{}, map[string]interface{}{}},
+		{"click text selector type", &clickStep{}, map[string]interface{}{"selector": "Login", "selectorType": "text"}},
+		{"type unknown selector type", &typeStep{}, map[string]interface{}{"selector": "#user", "selectorType": "id"}},
+		{"select without value or text", &selectStep{}, map[string]interface{}{"selector": "#country"}},
+		{"submit without selector", &submitStep{}, map[string]interface{}{"selectorType": "xpath"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.step.Init(tt.name, tt.input); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
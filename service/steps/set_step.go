@@ -0,0 +1,61 @@
+package steps
+
+import (
+	"context"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const setStepType = "set-step"
+
+type setStepConf struct {
+	Variables map[string]string `validate:"required,min=1"`
+}
+
+// setStep saves the configured (already templated) values into the flow variables
+type setStep struct {
+	name string
+	conf setStepConf
+}
+
+func (s *setStep) GetType() string {
+	return setStepType
+}
+
+func (s *setStep) GetName() string {
+	return s.name
+}
+
+func (s *setStep) Init(name string, input map[string]interface{}) error {
+	var conf setStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *setStep) NeedsBrowser() bool {
+	return false
+}
+
+func (s *setStep) Run(_ context.Context, runCtx *RunContext) (*Result, error) {
+	for name, value := range s.conf.Variables {
+		runCtx.Vars.Set(name, value)
+		runCtx.Logger.Infof("variable '%s' was set", name)
+	}
+
+	return &Result{}, nil
+}
@@ -1,7 +1,10 @@
 package steps
 
 import (
+	"context"
+
 	"github.com/chromedp/chromedp"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -9,5 +12,50 @@ type StepInterface interface {
 	GetType() string
 	GetName() string
 	Init(name string, conf map[string]interface{}) error
-	Run(logger *log.Entry) chromedp.Tasks
+	// NeedsBrowser returns true if the step runs in the flow browser tab,
+	// flows without such steps never launch a browser
+	NeedsBrowser() bool
+	// Run executes the step, ctx holds the step deadline and the browser tab of the steps which need one.
+	// The result may be returned with an error, its timings are reported either way
+	Run(ctx context.Context, runCtx *RunContext) (*Result, error)
+}
+
+// RunContext is the state of the flow run shared by its steps
+type RunContext struct {
+	Logger *log.Entry
+	Vars   *Variables
+	// Artifacts is nil when the run artifacts are disabled
+	Artifacts ArtifactsInterface
+}
+
+// ArtifactsInterface stores files into the artifacts of the flow run
+type ArtifactsInterface interface {
+	// Write saves the file and returns its path
+	Write(name string, data []byte) (string, error)
+}
+
+// Result is the outcome of a step run
+type Result struct {
+	// Timings are the durations in milliseconds of the step phases
+	Timings map[string]float64
+	// Warning marks a passed step as a warning, the flow continues
+	Warning string
+	// CertificateExpiryDays is set by the steps checking a certificate
+	CertificateExpiryDays *float64
+}
+
+// browserStep is embedded by the steps which run chromedp tasks in the flow browser tab
+type browserStep struct{}
+
+func (browserStep) NeedsBrowser() bool {
+	return true
+}
+
+// runTasks executes the tasks in the browser tab held by ctx
+func runTasks(ctx context.Context, tasks chromedp.Tasks) (*Result, error) {
+	if chromedp.FromContext(ctx) == nil {
+		return nil, errors.New("step requires a browser")
+	}
+
+	return &Result{}, chromedp.Run(ctx, tasks)
 }
@@ -22,6 +22,28 @@ func (sf *stepFactoryImpl) NewStep(stepType string) (StepInterface, error) {
 		return &waitStep{}, nil
 	case validateStepType:
 		return &validateStep{}, nil
+	case extractStepType:
+		return &extractStep{}, nil
+	case setStepType:
+		return &setStep{}, nil
+	case clickStepType:
+		return &clickStep{}, nil
+	case typeStepType:
+		return &typeStep{}, nil
+	case selectStepType:
+		return &selectStep{}, nil
+	case submitStepType:
+		return &submitStep{}, nil
+	case assertStepType:
+		return &assertStep{}, nil
+	case httpStepType:
+		return &httpStep{}, nil
+	case tlsStepType:
+		return &tlsStep{}, nil
+	case dnsStepType:
+		return &dnsStep{}, nil
+	case tcpStepType:
+		return &tcpStep{}, nil
 	default:
 		return nil, errors.Errorf("Undefined step '%s'", stepType)
 	}
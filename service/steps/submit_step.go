@@ -0,0 +1,64 @@
+package steps
+
+import (
+	"context"
+
+	"github.com/chromedp/chromedp"
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const submitStepType = "submit-step"
+
+type submitStepConf struct {
+	Element elementSelector `mapstructure:",squash"`
+}
+
+// submitStep submits the form the selected element belongs to
+type submitStep struct {
+	browserStep
+
+	name string
+	conf submitStepConf
+}
+
+func (s *submitStep) GetType() string {
+	return submitStepType
+}
+
+func (s *submitStep) GetName() string {
+	return s.name
+}
+
+func (s *submitStep) Init(name string, input map[string]interface{}) error {
+	var conf submitStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *submitStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	return runTasks(ctx, s.tasks(runCtx.Logger))
+}
+
+func (s *submitStep) tasks(logger *log.Entry) chromedp.Tasks {
+	logger.Infof("submitting form of %s", s.conf.Element.Selector)
+
+	return append(s.conf.Element.waitTasks(),
+		chromedp.Submit(s.conf.Element.Selector, s.conf.Element.queryOptions()...),
+	)
+}
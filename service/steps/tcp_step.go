@@ -0,0 +1,177 @@
+package steps
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const tcpStepType = "tcp-step"
+
+const (
+	defaultTCPConnectTimeout = 10 * time.Second
+	defaultTCPReadTimeout    = 5 * time.Second
+	maxTCPResponseSize       = 64 << 10
+	tcpReadBufferSize        = 4 << 10
+)
+
+// tcp connection phases reported as step timings
+const (
+	tcpPhaseConnect  = "connect"
+	tcpPhaseResponse = "response"
+)
+
+type tcpStepConf struct {
+	Address        string `validate:"required,hostname_port"`
+	ConnectTimeout string
+	Send           string // payload written once connected
+	Read           bool   // read a banner or a response even without expect
+	ReadTimeout    string
+	Expect         string // regex the response must match
+
+	connectTimeoutParsed time.Duration
+	readTimeoutParsed    time.Duration
+	expectParsed         *regexp.Regexp
+}
+
+// tcpStep connects to a port, optionally sends a payload and asserts on the response
+type tcpStep struct {
+	name string
+	conf tcpStepConf
+}
+
+func (s *tcpStep) GetType() string {
+	return tcpStepType
+}
+
+func (s *tcpStep) GetName() string {
+	return s.name
+}
+
+func (s *tcpStep) Init(name string, input map[string]interface{}) error {
+	var conf tcpStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	conf.connectTimeoutParsed = defaultTCPConnectTimeout
+	if conf.ConnectTimeout != "" {
+		conf.connectTimeoutParsed, err = time.ParseDuration(conf.ConnectTimeout)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' connect timeout", s.GetType())
+		}
+	}
+
+	conf.readTimeoutParsed = defaultTCPReadTimeout
+	if conf.ReadTimeout != "" {
+		conf.readTimeoutParsed, err = time.ParseDuration(conf.ReadTimeout)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' read timeout", s.GetType())
+		}
+	}
+
+	if conf.Expect != "" {
+		conf.expectParsed, err = regexp.Compile(conf.Expect)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' expect regex", s.GetType())
+		}
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *tcpStep) NeedsBrowser() bool {
+	return false
+}
+
+func (s *tcpStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	result := &Result{Timings: map[string]float64{}}
+
+	connectCtx, cancel := context.WithTimeout(ctx, s.conf.connectTimeoutParsed)
+	defer cancel()
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(connectCtx, "tcp", s.conf.Address)
+	if err != nil {
+		return result, errors.Wrapf(err, "failed connecting to %s", s.conf.Address)
+	}
+	defer conn.Close()
+	result.Timings[tcpPhaseConnect] = float64(time.Since(start).Nanoseconds()) / 1e6
+
+	runCtx.Logger.Infof("connected to %s in %s", s.conf.Address, time.Since(start))
+
+	// the read and write deadline also stops the step once ctx is done
+	deadline := time.Now().Add(s.conf.readTimeoutParsed)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	start = time.Now()
+	if s.conf.Send != "" {
+		_, err = conn.Write([]byte(s.conf.Send))
+		if err != nil {
+			return result, errors.Wrapf(err, "failed sending payload to %s", s.conf.Address)
+		}
+	}
+
+	if !s.conf.Read && s.conf.expectParsed == nil {
+		return result, nil
+	}
+
+	response, err := s.read(conn)
+	result.Timings[tcpPhaseResponse] = float64(time.Since(start).Nanoseconds()) / 1e6
+	if err != nil {
+		return result, errors.Wrapf(err, "failed reading from %s", s.conf.Address)
+	}
+
+	runCtx.Logger.Infof("%s responded %q", s.conf.Address, response)
+
+	if s.conf.expectParsed != nil && !s.conf.expectParsed.Match(response) {
+		return result, errors.Errorf("response %q does not match '%s'", response, s.conf.Expect)
+	}
+
+	return result, nil
+}
+
+// read returns the first chunk of the response, or when expect is set reads until
+// the response matches, the connection is closed or the read deadline is reached
+func (s *tcpStep) read(conn net.Conn) ([]byte, error) {
+	var response []byte
+	buf := make([]byte, tcpReadBufferSize)
+
+	for len(response) < maxTCPResponseSize {
+		n, err := conn.Read(buf)
+		response = append(response, buf[:n]...)
+
+		if len(response) > 0 && (s.conf.expectParsed == nil || s.conf.expectParsed.Match(response)) {
+			return response, nil
+		}
+
+		if err != nil {
+			// a partial response is asserted as is
+			if len(response) > 0 {
+				return response, nil
+			}
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
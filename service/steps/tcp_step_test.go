@@ -0,0 +1,137 @@
+package steps
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTCPServer serves every connection with handle and returns the server address
+func newTestTCPServer(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestTCPStep(t *testing.T) {
+	closeAddress := newTestTCPServer(t, func(conn net.Conn) {})
+	bannerAddress := newTestTCPServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("220 mail.example.com ESMTP\r\n"))
+	})
+	splitBannerAddress := newTestTCPServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("220 mail"))
+		time.Sleep(50 * time.Millisecond)
+		_, _ = conn.Write([]byte(".example.com ESMTP\r\n"))
+	})
+	echoAddress := newTestTCPServer(t, func(conn net.Conn) {
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("PONG " + line))
+	})
+	silentAddress := newTestTCPServer(t, func(conn net.Conn) {
+		time.Sleep(time.Second)
+	})
+
+	// a closed listener leaves a port which refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening: %v", err)
+	}
+	refusedAddress := listener.Addr().String()
+	_ = listener.Close()
+
+	tests := []struct {
+		name       string
+		input      map[string]interface{}
+		wantErr    string
+		wantPhases []string
+	}{
+		{"connect", map[string]interface{}{"address": closeAddress}, "", []string{tcpPhaseConnect}},
+		{"banner", map[string]interface{}{"address": bannerAddress, "expect": `^220 .+ ESMTP`}, "", []string{tcpPhaseConnect, tcpPhaseResponse}},   //nolint // line length
+		{"banner mismatch", map[string]interface{}{"address": bannerAddress, "expect": `^SSH-2\.0`}, "does not match '^SSH-2\\.0'", nil},           //nolint // line length
+		{"banner read in chunks", map[string]interface{}{"address": splitBannerAddress, "expect": `example\.com ESMTP`}, "", nil},                  //nolint // line length
+		{"send", map[string]interface{}{"address": echoAddress, "send": "PING 1\n", "expect": `^PONG PING 1`}, "", []string{tcpPhaseResponse}},     //nolint // line length
+		{"send mismatch", map[string]interface{}{"address": echoAddress, "send": "PING 1\n", "expect": `^PONG PING 2`}, "does not match", nil},     //nolint // line length
+		{"read without expect", map[string]interface{}{"address": bannerAddress, "read": true}, "", []string{tcpPhaseResponse}},                    //nolint // line length
+		{"read timeout", map[string]interface{}{"address": silentAddress, "read": true, "readTimeout": "100ms"}, "i/o timeout", nil},               //nolint // line length
+		{"closed without response", map[string]interface{}{"address": closeAddress, "expect": "."}, "failed reading from", nil},                    //nolint // line length
+		{"refused", map[string]interface{}{"address": refusedAddress}, "failed connecting to " + refusedAddress, nil},                              //nolint // line length
+		{"connect timeout", map[string]interface{}{"address": "192.0.2.1:80", "connectTimeout": "50ms"}, "failed connecting to 192.0.2.1:80", nil}, //nolint // line length
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &tcpStep{}
+			if err := step.Init(tt.name, tt.input); err != nil {
+				t.Fatalf("failed initializing step: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			result, err := step.Run(ctx, newTestRunContext())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("expected no error got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("expected error %q got %v", tt.wantErr, err)
+			}
+
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("expected the step to stop on its timeouts, took %s", elapsed)
+			}
+
+			for _, phase := range tt.wantPhases {
+				if _, ok := result.Timings[phase]; !ok {
+					t.Fatalf("expected the %s timing got %v", phase, result.Timings)
+				}
+			}
+		})
+	}
+}
+
+func TestTCPStepInitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+	}{
+		{"no address", map[string]interface{}{}},
+		{"no port", map[string]interface{}{"address": "example.com"}},
+		{"invalid connect timeout", map[string]interface{}{"address": "example.com:25", "connectTimeout": "soon"}},
+		{"invalid read timeout", map[string]interface{}{"address": "example.com:25", "readTimeout": "soon"}},
+		{"invalid expect", map[string]interface{}{"address": "example.com:25", "expect": "("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&tcpStep{}).Init(tt.name, tt.input); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
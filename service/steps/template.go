@@ -0,0 +1,241 @@
+package steps
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const templateOpenDelim = "{{"
+
+var templateFuncs = template.FuncMap{
+	"uuid": func() string { return uuid.NewV4().String() },
+	"now":  time.Now,
+}
+
+// IsTemplated returns true if any of the step configuration values is a template, see parseTemplate
+func IsTemplated(conf map[string]interface{}) bool {
+	return isTemplatedValue(conf)
+}
+
+// IsTemplatedAt returns true if the configuration value at the key path is templated or is
+// within a templated value. Keys are matched case insensitively as when decoding the configuration,
+// keys of squashed structs are skipped
+func IsTemplatedAt(conf map[string]interface{}, keyPath []interface{}) bool {
+	var value interface{} = conf
+	for _, segment := range keyPath {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			key, ok := segment.(string)
+			if !ok {
+				return isTemplatedValue(v)
+			}
+			if item, ok := lookupKey(v, key); ok {
+				value = item
+			}
+		case []interface{}:
+			i, ok := segment.(int)
+			if !ok || i < 0 || i >= len(v) {
+				return isTemplatedValue(v)
+			}
+			value = v[i]
+		default:
+			return isTemplatedValue(v)
+		}
+	}
+
+	return isTemplatedValue(value)
+}
+
+// WithoutTemplates returns a copy of the configuration without its templated values,
+// lists holding a templated value are removed as a whole
+func WithoutTemplates(conf map[string]interface{}) map[string]interface{} {
+	stripped := make(map[string]interface{}, len(conf))
+	for key, value := range conf {
+		if isTemplatedValue(value) {
+			if nested, ok := value.(map[string]interface{}); ok {
+				stripped[key] = WithoutTemplates(nested)
+			}
+			continue
+		}
+		stripped[key] = value
+	}
+
+	return stripped
+}
+
+func lookupKey(values map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := values[key]; ok {
+		return value, true
+	}
+
+	for name, value := range values {
+		if strings.EqualFold(name, key) {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+func isTemplatedValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return parseTemplate(v) != nil
+	case map[string]interface{}:
+		for _, item := range v {
+			if isTemplatedValue(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if isTemplatedValue(item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// RenderConfig returns a copy of the step configuration with every string value
+// rendered as a template, the flow variables are available as {{ .vars.name }}
+func RenderConfig(conf map[string]interface{}, vars *Variables) (map[string]interface{}, error) {
+	data := map[string]interface{}{
+		"vars": vars.All(),
+	}
+
+	rendered, err := renderValue(conf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered.(map[string]interface{}), nil
+}
+
+func renderValue(value interface{}, data map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return renderString(v, data)
+	case map[string]interface{}:
+		if v == nil {
+			return v, nil
+		}
+
+		rendered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			renderedItem, err := renderValue(item, data)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed rendering key '%s'", key)
+			}
+			rendered[key] = renderedItem
+		}
+
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			renderedItem, err := renderValue(item, data)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed rendering item %d", i)
+			}
+			rendered[i] = renderedItem
+		}
+
+		return rendered, nil
+	default:
+		return value, nil
+	}
+}
+
+func renderString(text string, data map[string]interface{}) (string, error) {
+	tmpl := parseTemplate(text)
+	if tmpl == nil {
+		return text, nil
+	}
+
+	var buf bytes.Buffer
+	err := tmpl.Option("missingkey=error").Execute(&buf, data)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed executing template '%s'", text)
+	}
+
+	return buf.String(), nil
+}
+
+// parseTemplate returns the template of a templated value, nil when the value is not a template.
+// Values are templates when they parse and use the flow variables or the template functions, other
+// values such as a literal "{{" in a script are kept as is. A templated value renders "{{" as {{"{{"}}
+func parseTemplate(text string) *template.Template {
+	if !strings.Contains(text, templateOpenDelim) {
+		return nil
+	}
+
+	tmpl, err := template.New("config").Funcs(templateFuncs).Parse(text)
+	if err != nil || tmpl.Tree == nil || !usesTemplateData(tmpl.Tree.Root) {
+		return nil
+	}
+
+	return tmpl
+}
+
+// usesTemplateData returns true if the template node references the flow variables or calls a template function
+func usesTemplateData(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if usesTemplateData(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return usesTemplateData(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if usesTemplateData(cmd) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if usesTemplateData(arg) {
+				return true
+			}
+		}
+	case *parse.ChainNode:
+		return usesTemplateData(n.Node)
+	case *parse.FieldNode:
+		return n.Ident[0] == "vars"
+	case *parse.VariableNode:
+		return len(n.Ident) > 1 && n.Ident[0] == "$" && n.Ident[1] == "vars"
+	case *parse.IdentifierNode:
+		_, ok := templateFuncs[n.Ident]
+		return ok
+	case *parse.IfNode:
+		return usesBranchData(&n.BranchNode)
+	case *parse.RangeNode:
+		return usesBranchData(&n.BranchNode)
+	case *parse.WithNode:
+		return usesBranchData(&n.BranchNode)
+	case *parse.TemplateNode:
+		return usesTemplateData(n.Pipe)
+	}
+
+	return false
+}
+
+func usesBranchData(n *parse.BranchNode) bool {
+	return usesTemplateData(n.Pipe) || usesTemplateData(n.List) || usesTemplateData(n.ElseList)
+}
@@ -0,0 +1,98 @@
+package steps
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsTemplated(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"https://example.com", false},
+		{"{{ .vars.token }}", true},
+		{"Bearer {{ .vars.token }}", true},
+		{"{{ $.vars.token }}", true},
+		{"{{ if .vars.admin }}admin{{ end }}", true},
+		{"{{ uuid }}", true},
+		{`{{ now.Format "2006" }}`, true},
+		{`{{"{{"}} {{ .vars.name }}`, true},
+		{"function() {{ return 1 }}", false},
+		{"{{ .missing }}", false},
+		{"{{", false},
+		{`{{"{{"}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := IsTemplated(map[string]interface{}{"value": tt.value}); got != tt.want {
+				t.Fatalf("expected %t got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderConfig(t *testing.T) {
+	vars := NewVariables(map[string]string{"token": "secret", "name": "world"})
+
+	conf := map[string]interface{}{
+		"script":  "function() {{ return 1 }}",
+		"header":  "Bearer {{ .vars.token }}",
+		"escaped": `{{"{{"}} {{ .vars.name }} }}`,
+		"nested":  map[string]interface{}{"items": []interface{}{"{{ .vars.name }}", 3}},
+	}
+
+	rendered, err := RenderConfig(conf, vars)
+	if err != nil {
+		t.Fatalf("failed rendering: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"script":  "function() {{ return 1 }}",
+		"header":  "Bearer secret",
+		"escaped": "{{ world }}",
+		"nested":  map[string]interface{}{"items": []interface{}{"world", 3}},
+	}
+	if !reflect.DeepEqual(rendered, expected) {
+		t.Fatalf("expected %v got %v", expected, rendered)
+	}
+
+	if _, err := RenderConfig(map[string]interface{}{"url": "{{ .vars.missing }}"}, vars); err == nil {
+		t.Fatal("expected an error for a missing variable")
+	}
+}
+
+func TestWithoutTemplates(t *testing.T) {
+	conf := map[string]interface{}{
+		"url":     "{{ .vars.url }}",
+		"timeout": "10s",
+		"expect":  map[string]interface{}{"status": 200, "body": "{{ .vars.body }}"},
+		"headers": []interface{}{"a", "{{ .vars.b }}"},
+	}
+
+	expected := map[string]interface{}{
+		"timeout": "10s",
+		"expect":  map[string]interface{}{"status": 200},
+	}
+	if stripped := WithoutTemplates(conf); !reflect.DeepEqual(stripped, expected) {
+		t.Fatalf("expected %v got %v", expected, stripped)
+	}
+
+	for _, tt := range []struct {
+		keyPath []interface{}
+		want    bool
+	}{
+		{[]interface{}{"URL"}, true},
+		{[]interface{}{"Timeout"}, false},
+		{[]interface{}{"Expect", "Body"}, true},
+		{[]interface{}{"Expect", "Status"}, false},
+		{[]interface{}{"Headers", 0}, false},
+		{[]interface{}{"Headers", 1}, true},
+		{[]interface{}{"Element", "URL"}, true}, // squashed struct
+	} {
+		if got := IsTemplatedAt(conf, tt.keyPath); got != tt.want {
+			t.Errorf("%v: expected %t got %t", tt.keyPath, tt.want, got)
+		}
+	}
+}
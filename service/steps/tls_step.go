@@ -0,0 +1,178 @@
+package steps
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const tlsStepType = "tls-step"
+
+const defaultTLSWarnDays = 30
+
+// tls handshake phases reported as step timings
+const (
+	tlsPhaseConnect   = "connect"
+	tlsPhaseHandshake = "tls"
+)
+
+type tlsStepConf struct {
+	Address    string `validate:"required,hostname_port"`
+	ServerName string // sent as SNI and matched against the certificate, defaults to the address host
+	CAFile     string // PEM CAs verifying the chain instead of the system CAs
+	WarnDays   *int   `validate:"omitempty,gte=0"`
+	FailDays   int    `validate:"gte=0"`
+
+	rootCAs *x509.CertPool
+}
+
+// tlsStep handshakes with the address and verifies its certificate chain, hostname and expiry.
+// The step warns when the certificate expires within warnDays and fails within failDays
+type tlsStep struct {
+	name string
+	conf tlsStepConf
+}
+
+func (s *tlsStep) GetType() string {
+	return tlsStepType
+}
+
+func (s *tlsStep) GetName() string {
+	return s.name
+}
+
+func (s *tlsStep) Init(name string, input map[string]interface{}) error {
+	var conf tlsStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	if conf.ServerName == "" {
+		conf.ServerName, _, _ = net.SplitHostPort(conf.Address)
+	}
+
+	if conf.WarnDays == nil {
+		warnDays := defaultTLSWarnDays
+		conf.WarnDays = &warnDays
+	}
+
+	if conf.CAFile != "" {
+		caCert, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed reading step '%s' CA file", s.GetType())
+		}
+
+		conf.rootCAs = x509.NewCertPool()
+		if !conf.rootCAs.AppendCertsFromPEM(caCert) {
+			return errors.Errorf("failed parsing step '%s' CA file '%s'", s.GetType(), conf.CAFile)
+		}
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *tlsStep) NeedsBrowser() bool {
+	return false
+}
+
+func (s *tlsStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	result := &Result{Timings: map[string]float64{}}
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.conf.Address)
+	if err != nil {
+		return result, errors.Wrapf(err, "failed connecting to %s", s.conf.Address)
+	}
+	defer conn.Close()
+	result.Timings[tlsPhaseConnect] = float64(time.Since(start).Nanoseconds()) / 1e6
+
+	// the chain is verified after the handshake to report the expiry of untrusted certificates too
+	client := tls.Client(conn, &tls.Config{
+		ServerName:         s.conf.ServerName,
+		InsecureSkipVerify: true, //nolint:gosec // verified below
+	})
+
+	start = time.Now()
+	err = client.HandshakeContext(ctx)
+	if err != nil {
+		return result, errors.Wrapf(err, "failed tls handshake with %s", s.conf.Address)
+	}
+	result.Timings[tlsPhaseHandshake] = float64(time.Since(start).Nanoseconds()) / 1e6
+
+	certificates := client.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return result, errors.Errorf("%s did not present a certificate", s.conf.Address)
+	}
+
+	// the verified chain expires with its first expiring certificate, unrelated certificates sent
+	// by the server are ignored. The expiry of an invalid chain is the leaf expiry
+	chain, verifyErr := s.verify(certificates)
+	if verifyErr != nil {
+		chain = certificates[:1]
+	}
+
+	expiring := chain[0]
+	for _, cert := range chain[1:] {
+		if cert.NotAfter.Before(expiring.NotAfter) {
+			expiring = cert
+		}
+	}
+
+	days := time.Until(expiring.NotAfter).Hours() / 24
+	result.CertificateExpiryDays = &days
+	runCtx.Logger.Infof("certificate '%s' of %s expires in %.1f days", expiring.Subject.CommonName, s.conf.Address, days)
+
+	if verifyErr != nil {
+		return result, verifyErr
+	}
+
+	expiry := fmt.Sprintf("certificate '%s' expires on %s (%.1f days)", expiring.Subject.CommonName, expiring.NotAfter.Format(time.RFC3339), days) //nolint // line length
+	if days < float64(s.conf.FailDays) {
+		return result, errors.Errorf("%s, less than %d days", expiry, s.conf.FailDays)
+	}
+
+	if days < float64(*s.conf.WarnDays) {
+		result.Warning = fmt.Sprintf("%s, less than %d days", expiry, *s.conf.WarnDays)
+	}
+
+	return result, nil
+}
+
+// verify checks the chain against the system or the configured CAs and the certificate hostname,
+// returns the verified chain from the leaf to the root
+func (s *tlsStep) verify(certificates []*x509.Certificate) ([]*x509.Certificate, error) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := certificates[0].Verify(x509.VerifyOptions{
+		DNSName:       s.conf.ServerName,
+		Roots:         s.conf.rootCAs,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid certificate of %s", s.conf.Address)
+	}
+
+	return chains[0], nil
+}
@@ -0,0 +1,247 @@
+package steps
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and its key, issued by its parent or self signed
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, name string, notAfter time.Time, isCA bool, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("failed generating serial: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.DNSNames = []string{name}
+	}
+
+	issuer, issuerKey := template, key
+	if parent != nil {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("failed creating certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed parsing certificate: %v", err)
+	}
+
+	return &testCert{cert: cert, key: key}
+}
+
+// writeCAFile writes the CA certificate as PEM and returns its path
+func writeCAFile(t *testing.T, ca *testCert) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+	if err := ioutil.WriteFile(path, caPEM, 0o600); err != nil {
+		t.Fatalf("failed writing CA file: %v", err)
+	}
+
+	return path
+}
+
+// newTLSServer serves the leaf and the extra certificates and returns its address
+func newTLSServer(t *testing.T, leaf *testCert, extra ...*testCert) string {
+	t.Helper()
+
+	chain := [][]byte{leaf.cert.Raw}
+	for _, cert := range extra {
+		chain = append(chain, cert.cert.Raw)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: chain, PrivateKey: leaf.key}},
+	})
+	if err != nil {
+		t.Fatalf("failed listening: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestTLSStep(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Now()
+
+	ca := newTestCert(t, "Test CA", now.Add(365*day), true, nil)
+	caFile := writeCAFile(t, ca)
+
+	valid := newTestCert(t, "localhost", now.Add(90*day), false, ca)
+	expiring := newTestCert(t, "localhost", now.Add(10*day), false, ca)
+	expired := newTestCert(t, "localhost", now.Add(-day), false, ca)
+	// sent by misconfigured servers, not part of the verified chain
+	staleCA := newTestCert(t, "Stale CA", now.Add(2*day), true, nil)
+
+	tests := []struct {
+		name        string
+		leaf        *testCert
+		extra       []*testCert
+		input       map[string]interface{}
+		wantErr     string
+		wantWarning bool
+		wantDays    float64
+	}{
+		{
+			name:     "valid chain",
+			leaf:     valid,
+			extra:    []*testCert{ca},
+			input:    map[string]interface{}{"serverName": "localhost", "caFile": caFile},
+			wantDays: 90,
+		},
+		{
+			name:     "unrelated certificates are ignored",
+			leaf:     valid,
+			extra:    []*testCert{staleCA},
+			input:    map[string]interface{}{"serverName": "localhost", "caFile": caFile},
+			wantDays: 90,
+		},
+		{
+			name:     "untrusted CA",
+			leaf:     valid,
+			input:    map[string]interface{}{"serverName": "localhost"},
+			wantErr:  "invalid certificate",
+			wantDays: 90,
+		},
+		{
+			name:     "hostname mismatch",
+			leaf:     valid,
+			input:    map[string]interface{}{"serverName": "other.example.com", "caFile": caFile},
+			wantErr:  "invalid certificate",
+			wantDays: 90,
+		},
+		{
+			name:     "expired",
+			leaf:     expired,
+			input:    map[string]interface{}{"serverName": "localhost", "caFile": caFile},
+			wantErr:  "invalid certificate",
+			wantDays: -1,
+		},
+		{
+			name:        "warn window",
+			leaf:        expiring,
+			input:       map[string]interface{}{"serverName": "localhost", "caFile": caFile},
+			wantWarning: true,
+			wantDays:    10,
+		},
+		{
+			name:     "warn window disabled",
+			leaf:     expiring,
+			input:    map[string]interface{}{"serverName": "localhost", "caFile": caFile, "warnDays": 0},
+			wantDays: 10,
+		},
+		{
+			name:     "fail window",
+			leaf:     expiring,
+			input:    map[string]interface{}{"serverName": "localhost", "caFile": caFile, "failDays": 15},
+			wantErr:  "less than 15 days",
+			wantDays: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input["address"] = newTLSServer(t, tt.leaf, tt.extra...)
+
+			step := &tlsStep{}
+			if err := step.Init(tt.name, tt.input); err != nil {
+				t.Fatalf("failed initializing step: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			result, err := step.Run(ctx, newTestRunContext())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("expected no error got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("expected error %q got %v", tt.wantErr, err)
+			}
+
+			if (result.Warning != "") != tt.wantWarning {
+				t.Fatalf("expected warning %t got %q", tt.wantWarning, result.Warning)
+			}
+
+			if result.CertificateExpiryDays == nil {
+				t.Fatal("expected the certificate expiry")
+			}
+			if days := *result.CertificateExpiryDays; days < tt.wantDays-0.1 || days > tt.wantDays+0.1 {
+				t.Fatalf("expected the certificate to expire in %.0f days got %.2f", tt.wantDays, days)
+			}
+		})
+	}
+}
+
+func TestTLSStepConnectionError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening: %v", err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	step := &tlsStep{}
+	if err := step.Init("closed", map[string]interface{}{"address": address}); err != nil {
+		t.Fatalf("failed initializing step: %v", err)
+	}
+
+	if _, err := step.Run(context.Background(), newTestRunContext()); err == nil {
+		t.Fatal("expected a connection error")
+	}
+}
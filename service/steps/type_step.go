@@ -0,0 +1,79 @@
+package steps
+
+import (
+	"context"
+
+	"github.com/chromedp/chromedp"
+	"github.com/go-playground/validator/v10"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const typeStepType = "type-step"
+
+type typeStepConf struct {
+	Element elementSelector `mapstructure:",squash"`
+	Text    string
+	Clear   bool
+	Submit  bool
+}
+
+type typeStep struct {
+	browserStep
+
+	name string
+	conf typeStepConf
+}
+
+func (s *typeStep) GetType() string {
+	return typeStepType
+}
+
+func (s *typeStep) GetName() string {
+	return s.name
+}
+
+func (s *typeStep) Init(name string, input map[string]interface{}) error {
+	var conf typeStepConf
+	err := config.Decode(input, &conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
+	}
+
+	// validate conf using validate tags
+	err = validator.New().Struct(conf)
+	if err != nil {
+		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
+	}
+
+	s.name = name
+	s.conf = conf
+
+	return nil
+}
+
+func (s *typeStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	return runTasks(ctx, s.tasks(runCtx.Logger))
+}
+
+func (s *typeStep) tasks(logger *log.Entry) chromedp.Tasks {
+	// the typed text is not logged as it might be a credential
+	logger.Infof("typing %d characters into %s", len(s.conf.Text), s.conf.Element.Selector)
+
+	typeTasks := s.conf.Element.waitTasks()
+
+	if s.conf.Clear {
+		typeTasks = append(typeTasks, chromedp.Clear(s.conf.Element.Selector, s.conf.Element.queryOptions()...))
+	}
+
+	if s.conf.Text != "" {
+		typeTasks = append(typeTasks, chromedp.SendKeys(s.conf.Element.Selector, s.conf.Text, s.conf.Element.queryOptions()...))
+	}
+
+	if s.conf.Submit {
+		typeTasks = append(typeTasks, chromedp.Submit(s.conf.Element.Selector, s.conf.Element.queryOptions()...))
+	}
+
+	return typeTasks
+}
@@ -1,32 +1,56 @@
 package steps
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
+	"image"
 	_ "image/png" // png decode support
+	"io/ioutil"
+	"os"
+	"path"
 	"time"
 
 	"github.com/chromedp/chromedp"
 	"github.com/go-playground/validator/v10"
-	"github.com/mitchellh/mapstructure"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 const validateStepType = "validate-step"
-const pollFunction = `() => {
-		const unloaded_images = [...document.querySelectorAll("img")].filter((image) => { return image.height == 0 })
-		return unloaded_images.length == 0
-	}
-`
+
+const (
+	validateModeMD5   = "md5"
+	validateModePixel = "pixel"
+)
 
 type validateStepConf struct {
-	Hash     string `validate:"required"`
 	Selector string `validate:"required"`
+	Mode     string `validate:"omitempty,oneof=md5 ahash dhash phash pixel"`
+
+	// md5 and perceptual hash modes
+	Hash      string
+	Threshold int `validate:"gte=0,lte=64"`
+
+	// baseline image, used by the pixel mode and as the hash source when hash is not set
+	Baseline       string
+	Tolerance      float64       `validate:"gte=0,lte=100"`
+	ColorThreshold uint8         // max channel difference for pixels to be considered equal
+	IgnoreRegions  []imageRegion `validate:"dive"`
+
+	// folder the screenshot and diff images are written to when validation fails,
+	// defaults to the run artifacts or to the temp folder when artifacts are disabled
+	DiffFolder string
+
+	baselineImage image.Image
 }
 
 type validateStep struct {
+	browserStep
+
 	name string
 	conf validateStepConf
 }
@@ -41,7 +65,7 @@ func (s *validateStep) GetName() string {
 
 func (s *validateStep) Init(name string, input map[string]interface{}) error {
 	var conf validateStepConf
-	err := mapstructure.Decode(input, &conf)
+	err := config.Decode(input, &conf)
 	if err != nil {
 		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
 	}
@@ -52,47 +76,184 @@ func (s *validateStep) Init(name string, input map[string]interface{}) error {
 		return errors.Wrapf(err, "failed validating step '%s' configuration", s.GetType())
 	}
 
+	if conf.Mode == "" {
+		conf.Mode = validateModeMD5
+	}
+
+	switch {
+	case conf.Mode == validateModeMD5 && conf.Hash == "":
+		return errors.Errorf("failed validating step '%s' configuration: mode '%s' requires a hash", s.GetType(), conf.Mode)
+	case conf.Mode == validateModePixel && conf.Baseline == "":
+		return errors.Errorf("failed validating step '%s' configuration: mode '%s' requires a baseline", s.GetType(), conf.Mode) //nolint // line length
+	case conf.Hash == "" && conf.Baseline == "":
+		return errors.Errorf("failed validating step '%s' configuration: mode '%s' requires a hash or a baseline", s.GetType(), conf.Mode) //nolint // line length
+	}
+
+	if conf.Baseline != "" {
+		conf.baselineImage, err = readPNG(conf.Baseline)
+		if err != nil {
+			return errors.Wrapf(err, "failed loading step '%s' baseline", s.GetType())
+		}
+	}
+
+	if conf.Mode != validateModeMD5 && conf.Mode != validateModePixel {
+		if conf.Hash == "" {
+			baselineHash, err := imageHash(conf.baselineImage, conf.Mode)
+			if err != nil {
+				return errors.Wrapf(err, "failed hashing step '%s' baseline", s.GetType())
+			}
+			conf.Hash = formatImageHash(baselineHash)
+		}
+
+		_, err = parseImageHash(conf.Hash)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing step '%s' hash", s.GetType())
+		}
+	}
+
 	s.name = name
 	s.conf = conf
 
 	return nil
 }
 
-func (s *validateStep) Run(logger *log.Entry) chromedp.Tasks {
+func (s *validateStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	return runTasks(ctx, s.tasks(runCtx.Logger, runCtx.Artifacts))
+}
+
+func (s *validateStep) tasks(logger *log.Entry, artifacts ArtifactsInterface) chromedp.Tasks {
 	logger.Infof("Running validate step with conf %+v", s.conf)
 
 	validationTasks := chromedp.Tasks{}
 
 	validationTasks = append(validationTasks, chromedp.ActionFunc(func(ctx context.Context) error {
 		var buf []byte
-
-		const timeoutMS = 100
-		// wait for the image to load, this is required because isolated pages are ""loaded"" immediately
-		// and then JS pops in elements in the background
-		chromedp.PollFunction(pollFunction, nil, chromedp.WithPollingTimeout(timeoutMS*time.Millisecond))
-
 		err := chromedp.Screenshot(s.conf.Selector, &buf, chromedp.NodeVisible).Do(ctx)
-
 		if err != nil {
-			logger.Error("failed to take screenshot")
+			logger.WithError(err).Error("failed to take screenshot")
+			return errors.Wrapf(err, "failed taking step '%s' screenshot", s.GetType())
 		}
 
-		return s.validateScreenshot(logger, buf)
+		return s.validateScreenshot(logger, artifacts, buf)
 	}))
 
 	return validationTasks
 }
 
-func (s *validateStep) validateScreenshot(logger *log.Entry, buf []byte) error {
-	logger.Infof("validating test screenshot")
+func (s *validateStep) validateScreenshot(logger *log.Entry, artifacts ArtifactsInterface, buf []byte) error {
+	logger.Infof("validating test screenshot using %s", s.conf.Mode)
+
+	if s.conf.Mode == validateModeMD5 {
+		hash := md5.Sum(buf)
+		hashString := hex.EncodeToString(hash[:])
+
+		if hashString != s.conf.Hash {
+			return s.validationFailed(logger, artifacts, buf, nil,
+				errors.Errorf("different hash result. Expected <= %s got %s.", s.conf.Hash, hashString))
+		}
+
+		logger.Info("Done validating image hash")
+		return nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return errors.Wrap(err, "failed decoding screenshot")
+	}
+
+	if s.conf.Mode == validateModePixel {
+		return s.validatePixels(logger, artifacts, buf, img)
+	}
+
+	return s.validatePerceptualHash(logger, artifacts, buf, img)
+}
+
+func (s *validateStep) validatePerceptualHash(logger *log.Entry, artifacts ArtifactsInterface, buf []byte, img image.Image) error { //nolint // line length
+	hash, err := imageHash(img, s.conf.Mode)
+	if err != nil {
+		return err
+	}
+
+	// the hash was validated on init
+	expectedHash, _ := parseImageHash(s.conf.Hash)
+
+	distance := hammingDistance(expectedHash, hash)
+	if distance > s.conf.Threshold {
+		var diff *image.RGBA
+		if s.conf.baselineImage != nil {
+			result, err := pixelDiff(s.conf.baselineImage, img, s.conf.ColorThreshold, s.conf.IgnoreRegions)
+			if err == nil {
+				diff = result.diffImage
+			}
+		}
+
+		return s.validationFailed(logger, artifacts, buf, diff,
+			errors.Errorf("%s distance %d is above threshold %d. Expected hash %s got %s.",
+				s.conf.Mode, distance, s.conf.Threshold, s.conf.Hash, formatImageHash(hash)))
+	}
 
-	hash := md5.Sum(buf)
-	hashString := hex.EncodeToString(hash[:])
+	logger.Infof("Done validating image %s, distance %d", s.conf.Mode, distance)
+	return nil
+}
 
-	if hashString != s.conf.Hash {
-		return errors.Errorf("different hash result. Expected <= %s got %s.", s.conf.Hash, hashString)
+func (s *validateStep) validatePixels(logger *log.Entry, artifacts ArtifactsInterface, buf []byte, img image.Image) error { //nolint // line length
+	result, err := pixelDiff(s.conf.baselineImage, img, s.conf.ColorThreshold, s.conf.IgnoreRegions)
+	if err != nil {
+		return s.validationFailed(logger, artifacts, buf, nil, err)
+	}
+
+	difference := result.differencePercentage()
+	if difference > s.conf.Tolerance {
+		return s.validationFailed(logger, artifacts, buf, result.diffImage,
+			errors.Errorf("%.3f%% of the pixels are different (%d/%d). Expected <= %.3f%%.",
+				difference, result.differentPixels, result.comparedPixels, s.conf.Tolerance))
 	}
 
-	logger.Info("Done validating image hash")
+	logger.Infof("Done validating image pixels, %.3f%% different", difference)
 	return nil
 }
+
+// validationFailed writes the screenshot and the diff image, if one was created, to the diff folder
+// and adds their paths to the validation error
+func (s *validateStep) validationFailed(logger *log.Entry, artifacts ArtifactsInterface, buf []byte, diff *image.RGBA, validationErr error) error { //nolint // line length
+	prefix := fmt.Sprintf("%s-%d", s.name, time.Now().Unix())
+
+	screenshotPath, err := s.writeImage(artifacts, prefix+"-actual.png", buf)
+	if err != nil {
+		logger.WithError(err).Error("failed writing validation screenshot")
+		return validationErr
+	}
+
+	if diff == nil {
+		return errors.Wrapf(validationErr, "screenshot written to %s", screenshotPath)
+	}
+
+	diffBuf, err := encodePNG(diff)
+	if err == nil {
+		var diffPath string
+		diffPath, err = s.writeImage(artifacts, prefix+"-diff.png", diffBuf)
+		if err == nil {
+			return errors.Wrapf(validationErr, "screenshot written to %s, diff written to %s", screenshotPath, diffPath)
+		}
+	}
+
+	logger.WithError(err).Error("failed writing validation diff image")
+	return errors.Wrapf(validationErr, "screenshot written to %s", screenshotPath)
+}
+
+// writeImage writes the image to the diff folder if set, otherwise to the run artifacts
+func (s *validateStep) writeImage(artifacts ArtifactsInterface, name string, data []byte) (string, error) {
+	if s.conf.DiffFolder == "" && artifacts != nil {
+		return artifacts.Write(name, data)
+	}
+
+	folder := s.conf.DiffFolder
+	if folder == "" {
+		folder = os.TempDir()
+	}
+
+	imagePath := path.Join(folder, name)
+	err := ioutil.WriteFile(imagePath, data, 0644) //nolint:gosec // artifacts are not secret
+
+	return imagePath, err
+}
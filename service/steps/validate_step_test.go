@@ -0,0 +1,61 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestValidateStepScreenshotError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><p>no image</p></body></html>`)
+	}))
+	defer server.Close()
+
+	baseline := filepath.Join(t.TempDir(), "baseline.png")
+	file, err := os.Create(baseline)
+	if err != nil {
+		t.Fatalf("failed creating baseline: %v", err)
+	}
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("failed writing baseline: %v", err)
+	}
+	file.Close()
+
+	ctx := newTestTab(t)
+	if err := chromedp.Run(ctx, chromedp.Navigate(server.URL)); err != nil {
+		t.Fatalf("failed navigating: %v", err)
+	}
+
+	for _, mode := range []string{"md5", "phash", "pixel"} {
+		t.Run(mode, func(t *testing.T) {
+			input := map[string]interface{}{"selector": "#missing", "mode": mode, "baseline": baseline}
+			if mode == validateModeMD5 {
+				input["hash"] = "d41d8cd98f00b204e9800998ecf8427e" // md5 of an empty screenshot
+			}
+
+			step := &validateStep{}
+			if err := step.Init(mode, input); err != nil {
+				t.Fatalf("failed initializing step: %v", err)
+			}
+
+			stepCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+
+			_, err := step.Run(stepCtx, newTestRunContext())
+			if err == nil || !strings.Contains(err.Error(), "screenshot") {
+				t.Fatalf("expected the screenshot error got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package steps
+
+import (
+	"sync"
+)
+
+// Variables is a flow run scoped key/value store used to pass data between steps
+type Variables struct {
+	lock   sync.RWMutex
+	values map[string]string
+}
+
+func NewVariables(initial map[string]string) *Variables {
+	v := &Variables{
+		values: make(map[string]string, len(initial)),
+	}
+
+	for name, value := range initial {
+		v.values[name] = value
+	}
+
+	return v
+}
+
+func (v *Variables) Get(name string) (string, bool) {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	value, ok := v.values[name]
+
+	return value, ok
+}
+
+func (v *Variables) Set(name string, value string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.values[name] = value
+}
+
+// All returns a copy of all the stored variables
+func (v *Variables) All() map[string]string {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	values := make(map[string]string, len(v.values))
+	for name, value := range v.values {
+		values[name] = value
+	}
+
+	return values
+}
@@ -1,13 +1,12 @@
 package steps
 
 import (
+	"context"
 	"time"
 
-	"github.com/chromedp/chromedp"
 	"github.com/go-playground/validator/v10"
-	"github.com/mitchellh/mapstructure"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 const waitStepType = "wait-step"
@@ -33,7 +32,7 @@ func (s *waitStep) GetName() string {
 
 func (s *waitStep) Init(name string, input map[string]interface{}) error {
 	var conf waitStepConf
-	err := mapstructure.Decode(input, &conf)
+	err := config.Decode(input, &conf)
 	if err != nil {
 		return errors.Wrapf(err, "failed parsing step '%s' configuration", s.GetType())
 	}
@@ -56,10 +55,17 @@ func (s *waitStep) Init(name string, input map[string]interface{}) error {
 	return nil
 }
 
-func (s *waitStep) Run(logger *log.Entry) chromedp.Tasks {
-	logger.Infof("Waiting for %s", s.conf.Duration)
+func (s *waitStep) NeedsBrowser() bool {
+	return false
+}
+
+func (s *waitStep) Run(ctx context.Context, runCtx *RunContext) (*Result, error) {
+	runCtx.Logger.Infof("Waiting for %s", s.conf.Duration)
 
-	return chromedp.Tasks{
-		chromedp.Sleep(s.conf.durationParsed),
+	select {
+	case <-time.After(s.conf.durationParsed):
+		return &Result{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
@@ -0,0 +1,177 @@
+package tester
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	artifactsCaptureTimeout = 30 * time.Second
+	artifactsPruneInterval  = time.Hour
+	screenshotQuality       = 100
+
+	screenshotArtifact = "screenshot.png"
+	domArtifact        = "page.html"
+	urlArtifact        = "url.txt"
+	consoleArtifact    = "console.log"
+	errorArtifact      = "error.txt"
+)
+
+// runArtifacts collects the browser console output of a flow run and captures
+// the browser state into the run artifacts folder when the flow fails,
+// steps write their own files with Write
+type runArtifacts struct {
+	folder string
+
+	lock    sync.Mutex
+	console []string
+}
+
+func newRunArtifacts(artifactsFolder string, flowName string, runID string) *runArtifacts {
+	return &runArtifacts{
+		folder: path.Join(artifactsFolder, flowName, runID),
+	}
+}
+
+// listen collects console calls and uncaught exceptions of the browser tab
+func (a *runArtifacts) listen(browserCtx context.Context) {
+	chromedp.ListenTarget(browserCtx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			args := make([]string, 0, len(ev.Args))
+			for _, arg := range ev.Args {
+				if len(arg.Value) > 0 {
+					args = append(args, string(arg.Value))
+				} else {
+					args = append(args, arg.Description)
+				}
+			}
+			a.addConsoleLine(string(ev.Type), strings.Join(args, " "))
+		case *runtime.EventExceptionThrown:
+			details := ev.ExceptionDetails
+			text := details.Text
+			if details.Exception != nil && details.Exception.Description != "" {
+				text = details.Exception.Description
+			}
+			a.addConsoleLine("exception", fmt.Sprintf("%s (%s:%d:%d)", text, details.URL, details.LineNumber, details.ColumnNumber))
+		}
+	})
+}
+
+func (a *runArtifacts) addConsoleLine(level string, text string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.console = append(a.console, fmt.Sprintf("%s [%s] %s", time.Now().Format(time.RFC3339Nano), level, text))
+}
+
+// capture writes the page screenshot, DOM, URL, console output and the flow error into the artifacts folder,
+// browserCtx must not be the timed out flow context so the state can be captured after a timeout.
+// Only the error is written for flows without a browser (nil browserCtx)
+func (a *runArtifacts) capture(browserCtx context.Context, logger *log.Entry, flowErr error) {
+	a.write(logger, errorArtifact, []byte(flowErr.Error()))
+	if browserCtx == nil {
+		logger.Infof("flow artifacts written to %s", a.folder)
+		return
+	}
+
+	a.lock.Lock()
+	console := strings.Join(a.console, "\n")
+	a.lock.Unlock()
+	a.write(logger, consoleArtifact, []byte(console))
+
+	captureCtx, cancel := context.WithTimeout(browserCtx, artifactsCaptureTimeout)
+	defer cancel()
+
+	var screenshot []byte
+	var dom, url string
+	err := chromedp.Run(captureCtx,
+		chromedp.Location(&url),
+		chromedp.OuterHTML("html", &dom, chromedp.ByQuery),
+		chromedp.FullScreenshot(&screenshot, screenshotQuality),
+	)
+	if err != nil {
+		logger.WithError(errors.Wrap(err, "failed capturing browser state")).Error("failed capturing artifacts")
+	}
+
+	a.write(logger, urlArtifact, []byte(url))
+	a.write(logger, domArtifact, []byte(dom))
+	if len(screenshot) > 0 {
+		a.write(logger, screenshotArtifact, screenshot)
+	}
+
+	logger.Infof("flow artifacts written to %s", a.folder)
+}
+
+// Write saves a file into the run artifacts folder and returns its path
+func (a *runArtifacts) Write(name string, data []byte) (string, error) {
+	err := os.MkdirAll(a.folder, 0755) //nolint:gosec // artifacts are not secret
+	if err != nil {
+		return "", errors.Wrap(err, "failed creating artifacts folder")
+	}
+
+	artifactPath := path.Join(a.folder, name)
+	err = ioutil.WriteFile(artifactPath, data, 0644) //nolint:gosec // artifacts are not secret
+	if err != nil {
+		return "", errors.Wrapf(err, "failed writing artifact '%s'", name)
+	}
+
+	return artifactPath, nil
+}
+
+func (a *runArtifacts) write(logger *log.Entry, name string, data []byte) {
+	_, err := a.Write(name, data)
+	if err != nil {
+		logger.WithError(err).Error("failed writing artifact")
+	}
+}
+
+// pruneArtifacts deletes the run artifacts folders, <flow>/<runId>, last written before the cutoff
+// and returns the number of deleted runs
+func pruneArtifacts(artifactsFolder string, cutoff time.Time) (int, error) {
+	flows, err := ioutil.ReadDir(artifactsFolder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, errors.Wrap(err, "failed reading artifacts folder")
+	}
+
+	pruned := 0
+	for _, flow := range flows {
+		if !flow.IsDir() {
+			continue
+		}
+
+		flowFolder := path.Join(artifactsFolder, flow.Name())
+		runs, err := ioutil.ReadDir(flowFolder)
+		if err != nil {
+			return pruned, errors.Wrapf(err, "failed reading flow '%s' artifacts folder", flow.Name())
+		}
+
+		for _, run := range runs {
+			if !run.IsDir() || !run.ModTime().Before(cutoff) {
+				continue
+			}
+
+			err = os.RemoveAll(path.Join(flowFolder, run.Name()))
+			if err != nil {
+				return pruned, errors.Wrapf(err, "failed deleting flow '%s' run '%s' artifacts", flow.Name(), run.Name())
+			}
+			pruned++
+		}
+	}
+
+	return pruned, nil
+}
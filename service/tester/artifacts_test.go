@@ -0,0 +1,106 @@
+package tester
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRunArtifactsWrite(t *testing.T) {
+	artifactsFolder := t.TempDir()
+	artifacts := newRunArtifacts(artifactsFolder, "login", "run-1")
+
+	artifactPath, err := artifacts.Write("diff.png", []byte("png"))
+	if err != nil {
+		t.Fatalf("failed writing artifact: %v", err)
+	}
+
+	if expected := path.Join(artifactsFolder, "login", "run-1", "diff.png"); artifactPath != expected {
+		t.Fatalf("expected the artifact at %s got %s", expected, artifactPath)
+	}
+
+	data, err := ioutil.ReadFile(artifactPath)
+	if err != nil || string(data) != "png" {
+		t.Fatalf("expected the artifact content got %q %v", data, err)
+	}
+
+	// a file where the run folder should be fails the write
+	blocked := newRunArtifacts(artifactPath, "login", "run-2")
+	if _, err := blocked.Write("error.txt", nil); err == nil {
+		t.Fatal("expected an error writing under a file")
+	}
+}
+
+func TestRunArtifactsCaptureWithoutBrowser(t *testing.T) {
+	artifactsFolder := t.TempDir()
+	artifacts := newRunArtifacts(artifactsFolder, "api", "run-1")
+	artifacts.addConsoleLine("log", "ignored without a browser")
+
+	artifacts.capture(nil, log.NewEntry(log.StandardLogger()), errors.New("step 'ping' failed"))
+
+	files, err := ioutil.ReadDir(path.Join(artifactsFolder, "api", "run-1"))
+	if err != nil {
+		t.Fatalf("failed reading the run artifacts: %v", err)
+	}
+	if len(files) != 1 || files[0].Name() != errorArtifact {
+		t.Fatalf("expected only the error artifact got %v", files)
+	}
+
+	data, _ := ioutil.ReadFile(path.Join(artifactsFolder, "api", "run-1", errorArtifact))
+	if string(data) != "step 'ping' failed" {
+		t.Fatalf("expected the flow error got %q", data)
+	}
+}
+
+func TestPruneArtifacts(t *testing.T) {
+	artifactsFolder := t.TempDir()
+	now := time.Now()
+
+	runs := []struct {
+		flow string
+		run  string
+		age  time.Duration
+	}{
+		{"login", "old", 48 * time.Hour},
+		{"login", "recent", time.Hour},
+		{"checkout", "old", 72 * time.Hour},
+	}
+	for _, run := range runs {
+		if _, err := newRunArtifacts(artifactsFolder, run.flow, run.run).Write(errorArtifact, []byte("failed")); err != nil {
+			t.Fatalf("failed writing artifact: %v", err)
+		}
+		runFolder := path.Join(artifactsFolder, run.flow, run.run)
+		if err := os.Chtimes(runFolder, now.Add(-run.age), now.Add(-run.age)); err != nil {
+			t.Fatalf("failed setting the run folder time: %v", err)
+		}
+	}
+
+	// files next to the flow folders are kept
+	if err := ioutil.WriteFile(path.Join(artifactsFolder, "README"), nil, 0600); err != nil {
+		t.Fatalf("failed writing file: %v", err)
+	}
+
+	pruned, err := pruneArtifacts(artifactsFolder, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("failed pruning: %v", err)
+	}
+	if pruned != 2 {
+		t.Fatalf("expected 2 pruned runs got %d", pruned)
+	}
+
+	for _, run := range runs {
+		_, err := os.Stat(path.Join(artifactsFolder, run.flow, run.run))
+		if exists := err == nil; exists != (run.run == "recent") {
+			t.Errorf("flow '%s' run '%s': expected exists %t got %t", run.flow, run.run, run.run == "recent", exists)
+		}
+	}
+
+	if pruned, err := pruneArtifacts(path.Join(artifactsFolder, "missing"), now); err != nil || pruned != 0 {
+		t.Fatalf("expected nothing to prune in a missing folder got %d %v", pruned, err)
+	}
+}
@@ -0,0 +1,301 @@
+package tester
+
+import (
+	"sort"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
+	"github.com/orensho/thin-slack-blackbox-tester/service/browser"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
+	"github.com/orensho/thin-slack-blackbox-tester/service/steps"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// configValidator collects all the config errors with their position in the config file
+type configValidator struct {
+	conf            *config.TesterConfig
+	stepsFactory    steps.StepFactoryInterface
+	notifierFactory notifier.NotifierFactoryInterface
+	errs            config.ValidationErrors
+}
+
+// ValidateConfig validates the whole config before any flow is created: the config keys, the steps
+// and notifiers configurations, the step references, durations, URLs and cron specs. Definitions
+// and notifiers which no flow uses are returned as warnings
+func ValidateConfig(
+	conf *config.TesterConfig,
+	stepsFactory steps.StepFactoryInterface,
+	notifierFactory notifier.NotifierFactoryInterface,
+) config.ValidationErrors {
+	v := &configValidator{
+		conf:            conf,
+		stepsFactory:    stepsFactory,
+		notifierFactory: notifierFactory,
+	}
+
+	v.errs = append(v.errs, conf.ValidateKeys()...)
+
+	for _, name := range sortedNames(conf.Definitions) {
+		v.validateStep(name, conf.Definitions[name])
+	}
+
+	for _, name := range sortedNames(conf.Notifiers) {
+		v.validateNotifier(name, conf.Notifiers[name])
+	}
+
+	usedSteps := map[string]bool{}
+	usedNotifiers := map[string]bool{}
+	for _, name := range sortedFlowNames(conf.Flows) {
+		v.validateFlow(name, conf.Flows[name], usedSteps, usedNotifiers)
+	}
+
+	for _, name := range sortedNames(conf.Definitions) {
+		if !usedSteps[name] {
+			v.errs = append(v.errs, conf.Warnf(at("definitions", name), "step '%s' is not used by any flow", name))
+		}
+	}
+
+	for _, name := range sortedNames(conf.Notifiers) {
+		if !usedNotifiers[name] {
+			v.errs = append(v.errs, conf.Warnf(at("notifiers", name), "notifier '%s' is not used by any flow", name))
+		}
+	}
+
+	return v.errs
+}
+
+func (v *configValidator) errorf(configPath []interface{}, format string, args ...interface{}) {
+	v.errs = append(v.errs, v.conf.Errorf(configPath, format, args...))
+}
+
+func (v *configValidator) validateStep(name string, definition config.Definition) {
+	definitionPath := at("definitions", name)
+	if definition.Type == "" {
+		v.errorf(definitionPath, "step '%s' has no type", name)
+		return
+	}
+
+	step, err := v.stepsFactory.NewStep(definition.Type)
+	if err != nil {
+		v.errorf(append(copyPath(definitionPath), "type"), "step '%s': %v", name, err)
+		return
+	}
+
+	// templated values are only known on run, their keys and the other values can still be checked
+	err = step.Init(name, definition.Config)
+	templated := steps.IsTemplated(definition.Config)
+	v.validateInitError(definitionPath, "step", name, err, templated)
+	if !templated || err == nil || isUnknownKeysError(err) {
+		return
+	}
+
+	step, err = v.stepsFactory.NewStep(definition.Type)
+	if err != nil {
+		return
+	}
+	err = step.Init(name, steps.WithoutTemplates(definition.Config))
+	v.validateTemplatedInitError(definitionPath, name, definition.Config, err)
+}
+
+// validateTemplatedInitError reports the errors of a templated step configuration initialized without
+// its templated values, the decoding errors and the validation errors of the values which are not
+// templated. Other errors may be caused by the missing templated values
+func (v *configValidator) validateTemplatedInitError(definitionPath []interface{}, name string, conf map[string]interface{}, err error) { //nolint // line length
+	switch cause := errors.Cause(err).(type) {
+	case *mapstructure.Error:
+		v.errorf(append(copyPath(definitionPath), "config"), "step '%s': %v", name, err)
+	case validator.ValidationErrors:
+		for _, fieldErr := range cause {
+			// the namespace starts with the configuration struct name and uses the field names
+			keyPath := config.ParseKeyPath(fieldErr.Namespace())
+			if len(keyPath) > 0 && steps.IsTemplatedAt(conf, keyPath[1:]) {
+				continue
+			}
+
+			v.errorf(append(copyPath(definitionPath), "config"), "step '%s': %v", name, fieldErr)
+		}
+	}
+}
+
+func isUnknownKeysError(err error) bool {
+	_, ok := errors.Cause(err).(*config.UnknownKeysError)
+	return ok
+}
+
+func (v *configValidator) validateNotifier(name string, definition config.Definition) {
+	definitionPath := at("notifiers", name)
+	if definition.Type == "" {
+		v.errorf(definitionPath, "notifier '%s' has no type", name)
+		return
+	}
+
+	flowNotifier, err := v.notifierFactory.NewNotifier(definition.Type)
+	if err != nil {
+		v.errorf(append(copyPath(definitionPath), "type"), "notifier '%s': %v", name, err)
+		return
+	}
+
+	err = flowNotifier.Init(name, definition.Config)
+	v.validateInitError(definitionPath, "notifier", name, err, false)
+}
+
+// validateInitError reports every unknown configuration key at its position,
+// other errors are reported at the configuration
+func (v *configValidator) validateInitError(definitionPath []interface{}, kind string, name string, err error, keysOnly bool) { //nolint // line length
+	if err == nil {
+		return
+	}
+
+	if unknownKeys, ok := errors.Cause(err).(*config.UnknownKeysError); ok {
+		for _, key := range unknownKeys.Keys {
+			keyPath := append(append(copyPath(definitionPath), "config"), config.ParseKeyPath(key)...)
+			v.errorf(keyPath, "unknown key '%s' in %s '%s' config", key, kind, name)
+		}
+		return
+	}
+
+	if !keysOnly {
+		v.errorf(append(copyPath(definitionPath), "config"), "%s '%s': %v", kind, name, err)
+	}
+}
+
+func (v *configValidator) validateFlow(name string, flow config.Flow, usedSteps map[string]bool, usedNotifiers map[string]bool) { //nolint // line length
+	flowPath := at("flows", name)
+	configPath := append(copyPath(flowPath), "config")
+	conf := flow.Config
+
+	if conf.Frequency == "" {
+		v.errorf(configPath, "flow '%s' has no frequency", name)
+	} else if _, err := cron.ParseStandard(conf.Frequency); err != nil {
+		v.errorf(append(copyPath(configPath), "frequency"), "flow '%s' frequency: %v", name, err)
+	}
+
+	if conf.Timeout != nil {
+		v.validateDuration(append(copyPath(configPath), "timeout"), name, "timeout", *conf.Timeout)
+	}
+
+	if conf.TeardownTimeout != nil {
+		v.validateDuration(append(copyPath(configPath), "teardownTimeout"), name, "teardown timeout", *conf.TeardownTimeout)
+	}
+
+	for i, windowConf := range conf.Maintenance {
+		if _, err := newMaintenanceWindow(windowConf); err != nil {
+			v.errorf(append(copyPath(configPath), "maintenance", i), "flow '%s' maintenance window: %v", name, err)
+		}
+	}
+
+	if conf.Browser != nil {
+		v.validateBrowser(append(copyPath(configPath), "browser"), name, conf.Browser)
+	}
+
+	if conf.Notifications != nil {
+		v.validateNotifications(append(copyPath(configPath), "notifications"), name, conf.Notifications, usedNotifiers)
+	}
+
+	if len(flow.Steps) == 0 {
+		v.errorf(flowPath, "flow '%s' has no steps", name)
+	}
+
+	for _, phase := range []struct {
+		key   string
+		steps []config.FlowStep
+	}{
+		{"setup", flow.Setup},
+		{"steps", flow.Steps},
+		{"teardown", flow.Teardown},
+	} {
+		for i, stepConfig := range phase.steps {
+			stepPath := append(copyPath(flowPath), phase.key, i)
+			usedSteps[stepConfig.Step] = true
+
+			if _, ok := v.conf.Definitions[stepConfig.Step]; !ok {
+				v.errorf(stepPath, "flow '%s' uses undefined step '%s'", name, stepConfig.Step)
+			}
+
+			if _, err := newStepPolicy(stepConfig); err != nil {
+				v.errorf(stepPath, "flow '%s' step '%s' policy: %v", name, stepConfig.Step, err)
+			}
+		}
+	}
+}
+
+func (v *configValidator) validateDuration(configPath []interface{}, flowName string, field string, value string) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		v.errorf(configPath, "flow '%s' %s: %v", flowName, field, err)
+		return
+	}
+
+	if duration <= 0 {
+		v.errorf(configPath, "flow '%s' %s '%s' must be positive", flowName, field, value)
+	}
+}
+
+func (v *configValidator) validateBrowser(configPath []interface{}, flowName string, conf *config.BrowserConfig) {
+	if conf.Remote != "" {
+		if _, err := browser.RemoteURL(conf.Remote); err != nil {
+			v.errorf(append(copyPath(configPath), "remote"), "flow '%s' browser: %v", flowName, err)
+		}
+
+		if len(conf.Flags) > 0 {
+			v.errorf(append(copyPath(configPath), "flags"), "flow '%s' browser: chrome flags cannot be set on a remote browser", flowName) //nolint // line length
+		}
+	}
+
+	if _, err := browser.Emulation(conf); err != nil {
+		v.errorf(configPath, "flow '%s' browser: %v", flowName, err)
+	}
+}
+
+func (v *configValidator) validateNotifications(configPath []interface{}, flowName string, conf *config.NotificationsConfig, usedNotifiers map[string]bool) { //nolint // line length
+	// the notifiers are checked apart to position every undefined notifier
+	defined := make(map[string]notifier.NotifierInterface, len(v.conf.Notifiers))
+	for name := range v.conf.Notifiers {
+		defined[name] = nil
+	}
+
+	for i, name := range conf.Notifiers {
+		usedNotifiers[name] = true
+		if _, ok := defined[name]; !ok {
+			v.errorf(append(copyPath(configPath), "notifiers", i), "flow '%s' uses undefined notifier '%s'", flowName, name)
+			defined[name] = nil
+		}
+	}
+
+	if _, err := newFlowNotifications(conf, defined); err != nil {
+		v.errorf(configPath, "flow '%s' notifications: %v", flowName, err)
+	}
+}
+
+// at returns a config path, see config.TesterConfig.Errorf
+func at(segments ...interface{}) []interface{} {
+	return segments
+}
+
+// copyPath copies the path so appending to it does not change other paths
+func copyPath(configPath []interface{}) []interface{} {
+	return append([]interface{}{}, configPath...)
+}
+
+func sortedNames(definitions map[string]config.Definition) []string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func sortedFlowNames(flows map[string]config.Flow) []string {
+	names := make([]string, 0, len(flows))
+	for name := range flows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
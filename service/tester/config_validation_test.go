@@ -0,0 +1,290 @@
+package tester
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
+	"github.com/orensho/thin-slack-blackbox-tester/service/steps"
+	"gopkg.in/yaml.v3"
+)
+
+func loadTestConfig(t *testing.T, text string) *config.TesterConfig {
+	t.Helper()
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		t.Fatalf("failed parsing config: %v", err)
+	}
+
+	conf := &config.TesterConfig{}
+	if err := root.Decode(conf); err != nil {
+		t.Fatalf("failed decoding config: %v", err)
+	}
+	conf.Source = &config.Source{File: "config.yaml", Root: &root}
+
+	return conf
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string // errors and warnings by position, matched by prefix
+	}{
+		{
+			name: "valid",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      url: https://example.com
+notifiers:
+  hook:
+    type: webhook
+    config:
+      url: https://hooks.example.com
+flows:
+  main:
+    config:
+      frequency: '@every 1m'
+      timeout: 30s
+      notifications:
+        notifiers: [hook]
+    steps: [ping]
+`,
+		},
+		{
+			name: "unknown keys",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      url: https://example.com
+      expect:
+        stauts: [200]
+flows:
+  main:
+    confg: {}
+    config:
+      frequency: '@every 1m'
+    steps: [ping]
+`,
+			want: []string{
+				"config.yaml:7:9: unknown key 'expect.stauts' in step 'ping' config",
+				"config.yaml:10:5: unknown key 'confg'",
+			},
+		},
+		{
+			name: "undefined step",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      url: https://example.com
+flows:
+  main:
+    config:
+      frequency: '@every 1m'
+    steps:
+      - ping
+      - step: pong
+        retries: 1
+`,
+			want: []string{"config.yaml:12:9: flow 'main' uses undefined step 'pong'"},
+		},
+		{
+			name: "bad cron and durations",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      url: https://example.com
+flows:
+  main:
+    config:
+      frequency: every minute
+      timeout: 0s
+      teardownTimeout: -1m
+    steps: [ping]
+  other:
+    config:
+      frequency: '@every 1m'
+      timeout: soon
+    steps: [ping]
+`,
+			want: []string{
+				"config.yaml:9:7: flow 'main' frequency: expected exactly 5 fields",
+				"config.yaml:10:7: flow 'main' timeout '0s' must be positive",
+				"config.yaml:11:7: flow 'main' teardown timeout '-1m' must be positive",
+				"config.yaml:16:7: flow 'other' timeout: time: invalid duration",
+			},
+		},
+		{
+			name: "unused definition and notifier",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      url: https://example.com
+  unused:
+    type: http-step
+    config:
+      url: https://example.com
+notifiers:
+  hook:
+    type: webhook
+    config:
+      url: https://hooks.example.com
+flows:
+  main:
+    config:
+      frequency: '@every 1m'
+    steps: [ping]
+`,
+			want: []string{
+				"config.yaml:6:3: warning: step 'unused' is not used by any flow",
+				"config.yaml:11:3: warning: notifier 'hook' is not used by any flow",
+			},
+		},
+		{
+			name: "invalid step and notifier configs",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      method: FETCH
+notifiers:
+  hook:
+    type: pager
+flows:
+  main:
+    config:
+      frequency: '@every 1m'
+      notifications:
+        notifiers: [hook, missing]
+    steps: [ping]
+`,
+			want: []string{
+				"config.yaml:4:5: step 'ping': failed validating step 'http-step' configuration: Key: 'httpStepConf.Method'",
+				"config.yaml:8:5: notifier 'hook': Undefined notifier 'pager'",
+				"config.yaml:14:27: flow 'main' uses undefined notifier 'missing'",
+			},
+		},
+		{
+			name: "templated values are skipped",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      url: '{{ .vars.base }}/ping'
+      expect:
+        maxLatency: '{{ .vars.latency }}'
+flows:
+  main:
+    config:
+      frequency: '@every 1m'
+      variables:
+        base: https://example.com
+        latency: 1s
+    steps: [ping]
+`,
+		},
+		{
+			name: "keys and values of templated steps which are not templated are checked",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      url: '{{ .vars.base }}/ping'
+      method: FETCH
+      retry: 1
+flows:
+  main:
+    config:
+      frequency: '@every 1m'
+    steps: [ping]
+`,
+			want: []string{
+				"config.yaml:7:7: unknown key 'retry' in step 'ping' config",
+			},
+		},
+		{
+			name: "values of templated steps which are not templated are checked",
+			text: `
+definitions:
+  ping:
+    type: http-step
+    config:
+      url: '{{ .vars.base }}/ping'
+      method: FETCH
+flows:
+  main:
+    config:
+      frequency: '@every 1m'
+    steps: [ping]
+`,
+			want: []string{
+				"config.yaml:4:5: step 'ping': Key: 'httpStepConf.Method'",
+			},
+		},
+		{
+			name: "aliases and merge keys",
+			text: `
+definitions:
+  ping: &ping
+    type: http-step
+    config:
+      url: https://example.com
+  pong:
+    <<: *ping
+flows:
+  main:
+    config: &schedule
+      frequency: '@every 1m'
+      timeout: -5s
+    steps: [ping, pong]
+  copy:
+    config: *schedule
+    steps: [ping]
+`,
+			want: []string{
+				"config.yaml:12:7: flow 'copy' timeout '-5s' must be positive",
+				"config.yaml:12:7: flow 'main' timeout '-5s' must be positive",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := loadTestConfig(t, strings.TrimPrefix(tt.text, "\n"))
+			errs := ValidateConfig(conf, steps.NewStepFactory(), notifier.NewNotifierFactory())
+
+			if len(errs) != len(tt.want) {
+				t.Fatalf("expected %d errors got %d:\n%s", len(tt.want), len(errs), errs.Error())
+			}
+
+			sort.SliceStable(errs, func(i, j int) bool {
+				if errs[i].Line != errs[j].Line {
+					return errs[i].Line < errs[j].Line
+				}
+				return errs[i].Column < errs[j].Column
+			})
+			for i, err := range errs {
+				if !strings.HasPrefix(err.Error(), tt.want[i]) {
+					t.Errorf("expected %q got %q", tt.want[i], err.Error())
+				}
+			}
+		})
+	}
+}
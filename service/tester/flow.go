@@ -2,45 +2,117 @@ package tester
 
 import (
 	"context"
-	"errors"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chromedp/cdproto/runtime"
 	"github.com/orensho/thin-slack-blackbox-tester/service/service"
 
 	"github.com/chromedp/chromedp"
+	"github.com/hashicorp/go-multierror"
+	"github.com/orensho/thin-slack-blackbox-tester/service/browser"
 	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
 	"github.com/orensho/thin-slack-blackbox-tester/service/steps"
+	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 )
 
+// flowStep is a step reference of a flow, templated steps are created again on every run
+type flowStep struct {
+	name         string
+	definition   config.Definition
+	policy       stepPolicy
+	needsBrowser bool
+	step         steps.StepInterface // nil when the step configuration is templated
+}
+
 type flow struct {
 	name           string
 	config         config.FlowConfig
-	steps          []steps.StepInterface
+	setup          []*flowStep
+	steps          []*flowStep
+	teardown       []*flowStep
+	stepsFactory   steps.StepFactoryInterface
 	rootCtx        context.Context
+	testerSettings *config.TesterSettings
 	metricsService service.MetricsServiceInterface
+	browserPool    browser.PoolInterface
+	notifications  *flowNotifications   // nil when the flow has no notifiers
+	sends          *sync.WaitGroup      // notifications in progress, waited for by the manager on stop
+	runHistory     RunHistoryInterface  // nil when the run history is disabled
+	reporter       RunReporterInterface // nil when no reports are configured
 
-	timeout time.Duration // calculated from config
+	timeout         time.Duration        // calculated from config
+	teardownTimeout time.Duration        // calculated from config
+	maintenance     []*maintenanceWindow // calculated from config
+	emulation       chromedp.Tasks       // calculated from config
+	needsBrowser    bool                 // calculated from the steps, flows without browser steps never open a tab
+
+	state      *flowState // shared with the flow replacing it on reload
+	retireLock sync.Mutex
+	active     bool   // set while a run of this flow is in progress, unlike state.running
+	onRetired  func() // called once the run in progress ends, see retire
 }
 
-var DefaultTimeout = time.Minute * 10
+// flowState is the runtime state of a flow, a flow changed by a reload shares the state of the
+// flow it replaces so a run in progress still blocks new runs and its result is kept
+type flowState struct {
+	running        int32 // set while a run is in progress, accessed atomically
+	paused         int32 // set while the flow is paused, accessed atomically
+	lastResultLock sync.RWMutex
+	lastResult     *RunResult
+}
+
+var (
+	DefaultTimeout         = time.Minute * 10
+	DefaultTeardownTimeout = time.Minute
+)
 
 func newFlow(
 	rootCtx context.Context,
 	name string,
 	conf config.FlowConfig,
-	flowSteps []steps.StepInterface,
+	setupSteps []*flowStep,
+	flowSteps []*flowStep,
+	teardownSteps []*flowStep,
+	stepsFactory steps.StepFactoryInterface,
+	testerSettings *config.TesterSettings,
 	metricsService service.MetricsServiceInterface,
+	browserPool browser.PoolInterface,
+	notifiers map[string]notifier.NotifierInterface,
+	sends *sync.WaitGroup,
+	runHistory RunHistoryInterface,
+	reporter RunReporterInterface,
 ) (*flow, error) {
 	flow := &flow{
-		name:           name,
-		config:         conf,
-		steps:          flowSteps,
-		timeout:        DefaultTimeout,
-		rootCtx:        rootCtx,
-		metricsService: metricsService,
+		name:         name,
+		config:       conf,
+		setup:        setupSteps,
+		steps:        flowSteps,
+		teardown:     teardownSteps,
+		stepsFactory: stepsFactory,
+		timeout:      DefaultTimeout,
+
+		teardownTimeout: DefaultTeardownTimeout,
+		rootCtx:         rootCtx,
+		testerSettings:  testerSettings,
+		metricsService:  metricsService,
+		browserPool:     browserPool,
+		sends:           sends,
+		runHistory:      runHistory,
+		reporter:        reporter,
+		state:           &flowState{},
+	}
+
+	for _, flowSteps := range [][]*flowStep{setupSteps, flowSteps, teardownSteps} {
+		for _, flowStep := range flowSteps {
+			flow.needsBrowser = flow.needsBrowser || flowStep.needsBrowser
+		}
 	}
 
 	if conf.Timeout != nil {
@@ -51,108 +123,626 @@ func newFlow(
 		flow.timeout = timeout
 	}
 
+	if conf.TeardownTimeout != nil {
+		teardownTimeout, err := time.ParseDuration(*conf.TeardownTimeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing teardown timeout '%s'", *conf.TeardownTimeout)
+		}
+		flow.teardownTimeout = teardownTimeout
+	}
+
+	for i, windowConf := range conf.Maintenance {
+		window, err := newMaintenanceWindow(windowConf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid maintenance window %d", i)
+		}
+		flow.maintenance = append(flow.maintenance, window)
+	}
+
+	emulation, err := browser.Emulation(conf.Browser)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid browser config")
+	}
+	flow.emulation = emulation
+
+	if conf.Notifications != nil {
+		notifications, err := newFlowNotifications(conf.Notifications, notifiers)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid notifications")
+		}
+		flow.notifications = notifications
+	}
+
 	return flow, nil
 }
 
+// Run is the cron job entry point, runs are skipped while the flow is paused,
+// in a maintenance window or a previous run is in progress
 func (f *flow) Run() {
+	if f.isPaused() {
+		log.WithField("flow", f.name).Infof("flow %s is paused, skipping run", f.name)
+		return
+	}
+
+	if f.inMaintenance(time.Now()) {
+		log.WithField("flow", f.name).Infof("flow %s is in a maintenance window, skipping run", f.name)
+		return
+	}
+
+	if !f.tryStart() {
+		log.WithField("flow", f.name).Warnf("flow %s is still running, skipping run", f.name)
+		return
+	}
+	defer f.done()
+
+	f.run(uuid.NewV4())
+}
+
+// tryStart marks the flow as running, returns false if it is already running
+func (f *flow) tryStart() bool {
+	f.retireLock.Lock()
+	defer f.retireLock.Unlock()
+
+	if !atomic.CompareAndSwapInt32(&f.state.running, 0, 1) {
+		return false
+	}
+	f.active = true
+
+	return true
+}
+
+func (f *flow) done() {
+	f.retireLock.Lock()
+	onRetired := f.onRetired
+	f.onRetired = nil
+	f.active = false
+	f.retireLock.Unlock()
+
+	// called before the flow replacing it can run
+	if onRetired != nil {
+		onRetired()
+	}
+
+	atomic.StoreInt32(&f.state.running, 0)
+}
+
+// retire is called once the flow was removed or replaced by a reload, onRetired is called
+// once the run in progress ends or right away when the flow is not running
+func (f *flow) retire(onRetired func()) {
+	f.retireLock.Lock()
+	if f.active {
+		f.onRetired = onRetired
+		f.retireLock.Unlock()
+		return
+	}
+	f.retireLock.Unlock()
+
+	onRetired()
+}
+
+func (f *flow) setPaused(paused bool) {
+	var value int32
+	if paused {
+		value = 1
+	}
+
+	atomic.StoreInt32(&f.state.paused, value)
+}
+
+func (f *flow) isPaused() bool {
+	return atomic.LoadInt32(&f.state.paused) == 1
+}
+
+func (f *flow) inMaintenance(now time.Time) bool {
+	for _, window := range f.maintenance {
+		if window.active(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LastResult returns a copy of the latest run result, nil if the flow never ran
+func (f *flow) LastResult() *RunResult {
+	f.state.lastResultLock.RLock()
+	defer f.state.lastResultLock.RUnlock()
+
+	if f.state.lastResult == nil {
+		return nil
+	}
+
+	return f.state.lastResult.copy()
+}
+
+func (f *flow) setLastResult(result *RunResult) {
+	f.state.lastResultLock.Lock()
+	defer f.state.lastResultLock.Unlock()
+
+	f.state.lastResult = result.copy()
+}
+
+func (f *flow) run(runID uuid.UUID) *RunResult {
 	logger := log.WithFields(log.Fields{
 		"flow":  f.name,
-		"runId": uuid.NewV4(), // unique id per run for easy logs debugging
+		"runId": runID, // unique id per run for easy logs debugging
 	})
 	logger.Infof("Starting flow %s", f.name)
 
-	browserCtx, cancelFunc := f.createTabContext(logger)
-	defer cancelFunc() // releases resources
-	f.stepsRun(browserCtx, logger)
+	result := &RunResult{
+		RunID:     runID.String(),
+		Flow:      f.name,
+		Status:    StatusRunning,
+		StartTime: time.Now(),
+		Steps:     []*StepResult{},
+	}
+	f.setLastResult(result)
+	defer func() {
+		f.setLastResult(result)
+		f.saveRun(logger, result)
+		f.writeReports(logger, result)
+		f.reportRunMetrics(logger, result)
+		f.notify(logger, result)
+	}()
+
+	// steps run in the browser tab, or in the root context when the flow has no browser steps
+	runCtx := f.rootCtx
+	var browserCtx context.Context
+	if f.needsBrowser {
+		// the tab is opened before applying the flow timeout, a timed out flow context
+		// would otherwise close the tab before its state can be captured
+		tabCtx, cancelFunc, err := f.browserPool.NewTab(f.rootCtx)
+		if err != nil {
+			logger.WithError(err).Errorf("failed opening browser for flow %s", f.name)
+			result.finish(StatusError, err)
+			return result
+		}
+		defer cancelFunc() // releases the tab
+
+		browserCtx = tabCtx
+		runCtx = tabCtx
+	}
+
+	stepsRunCtx := &steps.RunContext{
+		Logger: logger,
+		Vars:   steps.NewVariables(f.config.Variables),
+	}
+
+	var artifacts *runArtifacts
+	if f.testerSettings.ArtifactsFolder != "" {
+		artifacts = newRunArtifacts(f.testerSettings.ArtifactsFolder, f.name, runID.String())
+		stepsRunCtx.Artifacts = artifacts
+		if browserCtx != nil {
+			artifacts.listen(browserCtx)
+		}
+	}
+
+	flowCtx, flowCancel := context.WithTimeout(runCtx, f.timeout)
+	defer flowCancel()
+
+	err := f.mainRun(flowCtx, logger, stepsRunCtx, result)
+	if err != nil && artifacts != nil {
+		artifacts.capture(browserCtx, logger, err)
+		result.Artifacts = f.artifactsLink(result.RunID)
+	}
+
+	// the teardown runs even after a failure or a timeout, with its own time budget
+	f.teardownRun(runCtx, logger, stepsRunCtx, result)
+
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.finish(StatusTimeout, err)
+		} else {
+			result.finish(StatusError, err)
+		}
+		return result
+	}
+
+	if warnings := result.warnings(); warnings != nil {
+		result.finish(StatusWarning, warnings)
+		logger.Warnf("Finished flow %s with failed steps", f.name)
+		return result
+	}
 
+	result.finish(StatusSuccess, nil)
 	logger.Infof("Finished flow successfully %s", f.name)
+
+	return result
 }
 
-func (f *flow) stepsRun(browserCtx context.Context, logger *log.Entry) {
-	for _, step := range f.steps {
-		step := step // avoid closure
+// mainRun applies the browser config and executes the setup and the flow steps
+func (f *flow) mainRun(flowCtx context.Context, logger *log.Entry, runCtx *steps.RunContext, result *RunResult) error {
+	if f.needsBrowser && len(f.emulation) > 0 {
+		err := chromedp.Run(flowCtx, f.emulation)
+		if err != nil {
+			logger.WithError(err).Errorf("failed applying browser config for flow %s", f.name)
+			return errors.Wrap(err, "failed applying browser config")
+		}
+	}
 
-		logger = logger.WithFields(log.Fields{
-			"step": step.GetName(),
-		})
+	err := f.stepsRun(flowCtx, logger, f.setup, runCtx, result, &result.Setup)
+	if err != nil {
+		f.skipSteps(&result.Steps, f.steps)
+		f.setLastResult(result)
+		return errors.Wrap(err, "setup failed")
+	}
 
-		logger.Infof("executing step '%s'", step.GetName())
-		stepStartTime := time.Now()
+	return f.stepsRun(flowCtx, logger, f.steps, runCtx, result, &result.Steps)
+}
+
+// teardownRun executes all the teardown steps, a failed teardown step does not stop the next ones.
+// The teardown result is reported apart from the flow result
+func (f *flow) teardownRun(ctx context.Context, logger *log.Entry, runCtx *steps.RunContext, result *RunResult) {
+	if len(f.teardown) == 0 {
+		return
+	}
 
-		// execute the tasks returned from the step
-		err := chromedp.Run(browserCtx, step.Run(logger))
+	teardownCtx, teardownCancel := context.WithTimeout(ctx, f.teardownTimeout)
+	defer teardownCancel()
 
-		ms := float64(time.Since(stepStartTime).Nanoseconds()) / 1e6
-		logger.Infof("flow duration %fms", ms)
-		errMetrics := f.metricsService.ReportStepTestDuration(browserCtx, f.name, ms, step.GetName())
-		if errMetrics != nil {
-			logger.WithError(errMetrics).Error("failed reporting step duration")
+	var teardownErr *multierror.Error
+	for _, flowStep := range f.teardown {
+		stepLogger := logger.WithFields(log.Fields{
+			"step":     flowStep.name,
+			"teardown": true,
+		})
+
+		stepResult := &StepResult{
+			Name:   flowStep.name,
+			Type:   flowStep.definition.Type,
+			Status: StatusRunning,
 		}
+		result.Teardown = append(result.Teardown, stepResult)
 
-		// failure
+		err := f.stepRun(teardownCtx, stepLogger, flowStep, runCtx, result, stepResult)
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) {
-				errReport := f.metricsService.ReportStepTestTimeout(f.rootCtx, f.name, step.GetName())
-				if errReport != nil {
-					logger.WithError(errReport).Error("failed reporting step timeout")
-				}
-
-				logger.WithError(err).
-					Errorf("flow timeout after %s in step '%s'", f.timeout.String(), step.GetName())
-			} else {
-				errReport := f.metricsService.ReportStepTestError(f.rootCtx, f.name, step.GetName())
-				if errReport != nil {
-					logger.WithError(errReport).Error("failed reporting step error")
-				}
-
-				var cdpErr *runtime.ExceptionDetails
-				if errors.As(err, &cdpErr) && cdpErr.Exception != nil {
-					logger.
-						WithError(err).
-						WithField("ErrClassName", cdpErr.Exception.ClassName).
-						WithField("ErrDescription", cdpErr.Exception.Description).
-						Errorf("executing step '%s' returned an error, stopping flow", step.GetName())
-				} else {
-					logger.WithError(err).
-						Errorf("executing step '%s' returned an error, stopping flow", step.GetName())
-				}
+			stepResult.Error = err.Error()
+			f.setLastResult(result)
+			teardownErr = multierror.Append(teardownErr, errors.Wrapf(err, "teardown step '%s' failed", flowStep.name))
+		}
+	}
+
+	if err := teardownErr.ErrorOrNil(); err != nil {
+		result.TeardownStatus = StatusError
+		result.TeardownError = err.Error()
+		logger.WithError(err).Errorf("teardown of flow %s failed", f.name)
+
+		errReport := f.metricsService.ReportFlowTeardownFailure(f.rootCtx, f.name)
+		if errReport != nil {
+			logger.WithError(errReport).Error("failed reporting teardown failure")
+		}
+		return
+	}
+
+	result.TeardownStatus = StatusSuccess
+}
+
+// notify sends a notification when the flow starts failing or recovers
+func (f *flow) notify(logger *log.Entry, result *RunResult) {
+	if f.notifications == nil {
+		return
+	}
+
+	now := time.Now()
+	kind := f.notifications.notificationKind(result, now)
+	if kind == "" {
+		return
+	}
+
+	notification := &notifier.Notification{
+		Kind:                kind,
+		Flow:                f.name,
+		RunID:               result.RunID,
+		Status:              result.Status,
+		Environment:         f.testerSettings.Environment,
+		Error:               result.Error,
+		ConsecutiveFailures: f.notifications.failures(),
+		Time:                now,
+	}
+
+	if kind == notifier.KindFailure {
+		if failedStep := result.failedStep(); failedStep != nil {
+			notification.FailedStep = failedStep.Name
+		}
+		notification.Artifacts = result.Artifacts
+	}
+
+	f.notifications.send(logger, notification, f.sends)
+}
+
+// saveRun persists the finished run into the run history
+func (f *flow) saveRun(logger *log.Entry, result *RunResult) {
+	if f.runHistory == nil {
+		return
+	}
+
+	err := f.runHistory.Save(result)
+	if err != nil {
+		logger.WithError(err).Errorf("failed saving run of flow %s", f.name)
+	}
+}
+
+// writeReports writes the finished run reports
+func (f *flow) writeReports(logger *log.Entry, result *RunResult) {
+	if f.reporter == nil {
+		return
+	}
+
+	err := f.reporter.Report([]*RunResult{result})
+	if err != nil {
+		logger.WithError(err).Errorf("failed writing reports of flow %s", f.name)
+	}
+}
+
+// artifactsLink returns the URL of the run artifacts if configured, otherwise their local folder
+func (f *flow) artifactsLink(runID string) string {
+	if f.testerSettings.ArtifactsFolder == "" {
+		return ""
+	}
+
+	if f.testerSettings.ArtifactsURL != "" {
+		return strings.TrimSuffix(f.testerSettings.ArtifactsURL, "/") + "/" + path.Join(f.name, runID)
+	}
+
+	return path.Join(f.testerSettings.ArtifactsFolder, f.name, runID)
+}
+
+// reportRunMetrics reports the flow level metrics of a finished run
+func (f *flow) reportRunMetrics(logger *log.Entry, result *RunResult) {
+	var metricsErr *multierror.Error
+
+	metricsResult := service.FlowResultFailure
+	switch result.Status {
+	case StatusSuccess:
+		metricsResult = service.FlowResultSuccess
+	case StatusWarning:
+		metricsResult = service.FlowResultWarning
+	case StatusTimeout:
+		metricsResult = service.FlowResultTimeout
+	}
+
+	metricsErr = multierror.Append(metricsErr,
+		f.metricsService.ReportFlowDuration(f.rootCtx, f.name, result.DurationMS),
+		f.metricsService.ReportFlowResult(f.rootCtx, f.name, metricsResult),
+		f.metricsService.ReportFlowLastRun(f.rootCtx, f.name, result.StartTime),
+		f.metricsService.ReportFlowProbeSuccess(f.rootCtx, f.name, result.Passed()),
+	)
+
+	if result.Passed() {
+		metricsErr = multierror.Append(metricsErr, f.metricsService.ReportFlowLastSuccess(f.rootCtx, f.name, result.StartTime))
+	}
+
+	if err := metricsErr.ErrorOrNil(); err != nil {
+		logger.WithError(err).Error("failed reporting flow metrics")
+	}
+}
+
+// stepsRun executes the flow steps by order and returns the error of the step which stopped the flow,
+// steps with continueOnError are marked as warnings and do not stop the flow.
+// The run result is published on every step change so the latest result shows the running steps
+func (f *flow) stepsRun(flowCtx context.Context, logger *log.Entry, flowSteps []*flowStep, runCtx *steps.RunContext, result *RunResult, stepResults *[]*StepResult) error { //nolint // line length
+	for i, flowStep := range flowSteps {
+		stepLogger := logger.WithFields(log.Fields{
+			"step": flowStep.name,
+		})
+
+		stepResult := &StepResult{
+			Name:   flowStep.name,
+			Type:   flowStep.definition.Type,
+			Status: StatusRunning,
+		}
+		*stepResults = append(*stepResults, stepResult)
+
+		err := f.stepRun(flowCtx, stepLogger, flowStep, runCtx, result, stepResult)
+		if err == nil {
+			continue
+		}
+
+		stepResult.Error = err.Error()
+
+		// a flow timeout stops the flow even for steps with continueOnError
+		if flowStep.policy.continueOnError && flowCtx.Err() == nil {
+			stepResult.Status = StatusWarning
+			f.setLastResult(result)
+			stepLogger.WithError(err).Warnf("step '%s' failed, continuing flow", flowStep.name)
+			continue
+		}
+
+		f.skipSteps(stepResults, flowSteps[i+1:])
+		f.setLastResult(result)
+		return errors.Wrapf(err, "step '%s' failed", flowStep.name)
+	}
+
+	return nil
+}
+
+// stepRun executes a step, failed attempts are retried with an exponential backoff
+func (f *flow) stepRun(flowCtx context.Context, logger *log.Entry, flowStep *flowStep, runCtx *steps.RunContext, result *RunResult, stepResult *StepResult) error { //nolint // line length
+	step, err := f.prepareStep(flowStep, runCtx.Vars)
+	if err != nil {
+		stepResult.Status = StatusError
+
+		errReport := f.metricsService.ReportStepTestError(f.rootCtx, f.name, flowStep.name)
+		if errReport != nil {
+			logger.WithError(errReport).Error("failed reporting step error")
+		}
+
+		logger.WithError(err).
+			Errorf("preparing step '%s' returned an error", flowStep.name)
+		return err
+	}
+
+	backoff := flowStep.policy.backoff
+	for attempt := 1; ; attempt++ {
+		stepResult.Attempts = attempt
+		stepResult.Status = StatusRunning
+		f.setLastResult(result)
+
+		var stepRunResult *steps.Result
+		stepRunResult, err = f.stepAttempt(flowCtx, logger, flowStep.policy, step, runCtx, stepResult)
+		if err == nil {
+			stepResult.Status = StatusSuccess
+			if stepRunResult != nil && stepRunResult.Warning != "" {
+				stepResult.Status = StatusWarning
+				stepResult.Error = stepRunResult.Warning
+				logger.Warnf("step '%s' passed with a warning: %s", step.GetName(), stepRunResult.Warning)
+			}
+			f.setLastResult(result)
+			err = f.metricsService.ReportStepTestSuccess(f.rootCtx, f.name, step.GetName())
+			if err != nil {
+				logger.WithError(err).Error("failed reporting step success")
 			}
-			return
+			logger.Infof("finished successfully executing step '%s'", step.GetName())
+
+			return nil
+		}
+
+		stepResult.Status = StatusError
+		if errors.Is(err, context.DeadlineExceeded) {
+			stepResult.Status = StatusTimeout
+		}
+		f.setLastResult(result)
+
+		// no retry once the flow timed out
+		if attempt > flowStep.policy.retries || flowCtx.Err() != nil {
+			return err
+		}
+
+		logger.WithError(err).
+			Warnf("retrying step '%s' in %s, attempt %d of %d", step.GetName(), backoff, attempt+1, flowStep.policy.retries+1)
+
+		errReport := f.metricsService.ReportStepTestRetry(f.rootCtx, f.name, step.GetName())
+		if errReport != nil {
+			logger.WithError(errReport).Error("failed reporting step retry")
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-flowCtx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// stepAttempt executes the step once within the step timeout
+func (f *flow) stepAttempt(flowCtx context.Context, logger *log.Entry, policy stepPolicy, step steps.StepInterface, runCtx *steps.RunContext, stepResult *StepResult) (*steps.Result, error) { //nolint // line length
+	stepCtx := flowCtx
+	if policy.timeout > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(flowCtx, policy.timeout)
+		defer cancel()
+	}
+
+	logger.Infof("executing step '%s'", step.GetName())
+	stepStartTime := time.Now()
+
+	stepRunCtx := *runCtx
+	stepRunCtx.Logger = logger
+	stepRunResult, err := step.Run(stepCtx, &stepRunCtx)
+
+	ms := float64(time.Since(stepStartTime).Nanoseconds()) / 1e6
+	stepResult.DurationMS += ms
+	logger.Infof("flow duration %fms", ms)
+	errMetrics := f.metricsService.ReportStepTestDuration(f.rootCtx, f.name, ms, step.GetName())
+	if errMetrics != nil {
+		logger.WithError(errMetrics).Error("failed reporting step duration")
+	}
+
+	if stepRunResult != nil {
+		f.reportStepResult(logger, step.GetName(), stepRunResult)
+	}
+
+	if err == nil {
+		return stepRunResult, nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		errReport := f.metricsService.ReportStepTestTimeout(f.rootCtx, f.name, step.GetName())
+		if errReport != nil {
+			logger.WithError(errReport).Error("failed reporting step timeout")
+		}
+
+		if flowCtx.Err() != nil {
+			logger.WithError(err).
+				Errorf("flow timeout after %s in step '%s'", f.timeout.String(), step.GetName())
+		} else {
+			logger.WithError(err).
+				Errorf("step timeout after %s in step '%s'", policy.timeout.String(), step.GetName())
+		}
+
+		return nil, err
+	}
+
+	errReport := f.metricsService.ReportStepTestError(f.rootCtx, f.name, step.GetName())
+	if errReport != nil {
+		logger.WithError(errReport).Error("failed reporting step error")
+	}
+
+	var cdpErr *runtime.ExceptionDetails
+	if errors.As(err, &cdpErr) && cdpErr.Exception != nil {
+		logger.
+			WithError(err).
+			WithField("ErrClassName", cdpErr.Exception.ClassName).
+			WithField("ErrDescription", cdpErr.Exception.Description).
+			Errorf("executing step '%s' returned an error", step.GetName())
+	} else {
+		logger.WithError(err).
+			Errorf("executing step '%s' returned an error", step.GetName())
+	}
+
+	return nil, err
+}
+
+// reportStepResult reports the durations of the step phases and the step gauges
+func (f *flow) reportStepResult(logger *log.Entry, stepName string, result *steps.Result) {
+	for phase, ms := range result.Timings {
+		err := f.metricsService.ReportStepPhaseDuration(f.rootCtx, f.name, stepName, phase, ms)
+		if err != nil {
+			logger.WithError(err).Errorf("failed reporting step %s duration", phase)
 		}
+	}
 
-		// success
-		err = f.metricsService.ReportStepTestSuccess(f.rootCtx, f.name, step.GetName())
+	if result.CertificateExpiryDays != nil {
+		err := f.metricsService.ReportCertificateExpiry(f.rootCtx, f.name, stepName, *result.CertificateExpiryDays)
 		if err != nil {
-			logger.WithError(err).Error("failed reporting step success")
+			logger.WithError(err).Error("failed reporting certificate expiry")
 		}
-		logger.Infof("finished successfully executing step '%s'", step.GetName())
 	}
 }
 
-func (f *flow) createTabContext(logger *log.Entry) (context.Context, context.CancelFunc) {
-	// create flow context with timeout
-	flowContext, flowCancel := context.WithTimeout(f.rootCtx, f.timeout)
+// skipSteps adds the steps not executed after a failure to the run result
+func (f *flow) skipSteps(stepResults *[]*StepResult, flowSteps []*flowStep) {
+	for _, flowStep := range flowSteps {
+		*stepResults = append(*stepResults, &StepResult{
+			Name:   flowStep.name,
+			Type:   flowStep.definition.Type,
+			Status: StatusSkipped,
+		})
+	}
+}
 
-	// create the flags for the headless browser
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("ignore-certificate-errors", true),
-		chromedp.UserAgent("Firefox/80"),
-	)
+// prepareStep returns the step to execute, templated steps are rendered with the current
+// flow variables and initialized again so values saved by previous steps can be used
+func (f *flow) prepareStep(flowStep *flowStep, vars *steps.Variables) (steps.StepInterface, error) {
+	if flowStep.step != nil {
+		return flowStep.step, nil
+	}
 
-	// create a allocator with the new flags
-	allocCtx, allocCancel := chromedp.NewExecAllocator(flowContext, opts...)
+	stepConfig, err := steps.RenderConfig(flowStep.definition.Config, vars)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed rendering step '%s' configuration", flowStep.name)
+	}
 
-	// create browser context with the allocator and logging
-	browserCtx, browserCancel := chromedp.NewContext(
-		allocCtx,
-		chromedp.WithDebugf(logger.Debugf),
-		chromedp.WithLogf(logger.Infof),
-		chromedp.WithErrorf(logger.Errorf))
+	step, err := f.stepsFactory.NewStep(flowStep.definition.Type)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating step '%s'", flowStep.name)
+	}
 
-	return browserCtx, func() {
-		// call all created cancel func
-		browserCancel()
-		allocCancel()
-		flowCancel()
+	err = step.Init(flowStep.name, stepConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed initializing step '%s'", flowStep.name)
 	}
+
+	return step, nil
 }
@@ -0,0 +1,121 @@
+package tester
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/service"
+	"github.com/orensho/thin-slack-blackbox-tester/service/steps"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// testStep runs the given function
+type testStep struct {
+	name string
+	run  func() error
+}
+
+func (s *testStep) GetType() string { return "test-step" }
+
+func (s *testStep) GetName() string { return s.name }
+
+func (s *testStep) Init(name string, conf map[string]interface{}) error { return nil }
+
+func (s *testStep) NeedsBrowser() bool { return false }
+
+func (s *testStep) Run(ctx context.Context, runCtx *steps.RunContext) (*steps.Result, error) {
+	return nil, s.run()
+}
+
+func newTestFlowStep(name string, policy stepPolicy, run func() error) *flowStep {
+	return &flowStep{
+		name:       name,
+		definition: config.Definition{Type: "test-step"},
+		policy:     policy,
+		step:       &testStep{name: name, run: run},
+	}
+}
+
+func TestFlowLastResultShowsRunningSteps(t *testing.T) {
+	metricsService, err := service.NewMetricsService(&config.MetricsSettings{})
+	if err != nil {
+		t.Fatalf("failed creating metrics service: %v", err)
+	}
+
+	var f *flow
+	var seen []*RunResult
+	record := func() { seen = append(seen, f.LastResult()) }
+
+	attempts := 0
+	flowSteps := []*flowStep{
+		newTestFlowStep("first", stepPolicy{}, func() error {
+			record()
+			return nil
+		}),
+		newTestFlowStep("flaky", stepPolicy{retries: 1, backoff: time.Millisecond}, func() error {
+			record()
+			attempts++
+			if attempts == 1 {
+				return errors.New("first attempt fails")
+			}
+			return nil
+		}),
+	}
+	teardownSteps := []*flowStep{
+		newTestFlowStep("cleanup", stepPolicy{}, func() error {
+			record()
+			return nil
+		}),
+	}
+
+	f, err = newFlow(context.Background(), "flow", config.FlowConfig{}, nil, flowSteps, teardownSteps, nil,
+		&config.TesterSettings{}, metricsService, nil, nil, &sync.WaitGroup{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed creating flow: %v", err)
+	}
+
+	result := f.run(uuid.NewV4())
+	if result.Status != StatusSuccess {
+		t.Fatalf("expected a successful run got %s: %s", result.Status, result.Error)
+	}
+
+	type stepStatus struct {
+		name     string
+		status   string
+		attempts int
+	}
+	expected := [][]stepStatus{
+		{{"first", StatusRunning, 1}},
+		{{"first", StatusSuccess, 1}, {"flaky", StatusRunning, 1}},
+		{{"first", StatusSuccess, 1}, {"flaky", StatusRunning, 2}},
+		{{"first", StatusSuccess, 1}, {"flaky", StatusSuccess, 2}, {"cleanup", StatusRunning, 1}},
+	}
+
+	if len(seen) != len(expected) {
+		t.Fatalf("expected %d step runs got %d", len(expected), len(seen))
+	}
+
+	for i, latest := range seen {
+		if latest == nil || latest.Status != StatusRunning {
+			t.Fatalf("step run %d: expected a running result got %+v", i, latest)
+		}
+
+		stepResults := append(append([]*StepResult{}, latest.Steps...), latest.Teardown...)
+		if len(stepResults) != len(expected[i]) {
+			t.Fatalf("step run %d: expected %d steps got %d", i, len(expected[i]), len(stepResults))
+		}
+		for j, step := range stepResults {
+			if got := (stepStatus{step.Name, step.Status, step.Attempts}); got != expected[i][j] {
+				t.Errorf("step run %d: expected %+v got %+v", i, expected[i][j], got)
+			}
+		}
+	}
+
+	if latest := f.LastResult(); latest.Status != StatusSuccess || latest.TeardownStatus != StatusSuccess {
+		t.Fatalf("expected the finished result got %+v", latest)
+	}
+}
@@ -0,0 +1,79 @@
+package tester
+
+import (
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// maintenanceWindow is a time range in which the flow scheduled runs are skipped,
+// either recurring (a cron schedule of the window start and a duration) or absolute
+type maintenanceWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+
+	start time.Time
+	end   time.Time
+}
+
+func newMaintenanceWindow(conf config.MaintenanceWindow) (*maintenanceWindow, error) {
+	window := &maintenanceWindow{}
+
+	switch {
+	case conf.Schedule != "":
+		if conf.Start != "" || conf.End != "" {
+			return nil, errors.New("maintenance window can't have both a schedule and a start/end")
+		}
+
+		schedule, err := cron.ParseStandard(conf.Schedule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing maintenance schedule '%s'", conf.Schedule)
+		}
+
+		duration, err := time.ParseDuration(conf.Duration)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing maintenance duration '%s'", conf.Duration)
+		}
+		if duration <= 0 {
+			return nil, errors.Errorf("maintenance duration '%s' must be positive", conf.Duration)
+		}
+
+		window.schedule = schedule
+		window.duration = duration
+	case conf.Start != "" && conf.End != "":
+		start, err := time.Parse(time.RFC3339, conf.Start)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing maintenance start '%s'", conf.Start)
+		}
+
+		end, err := time.Parse(time.RFC3339, conf.End)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing maintenance end '%s'", conf.End)
+		}
+
+		if !end.After(start) {
+			return nil, errors.Errorf("maintenance end '%s' must be after start '%s'", conf.End, conf.Start)
+		}
+
+		window.start = start
+		window.end = end
+	default:
+		return nil, errors.New("maintenance window requires a schedule and duration or a start and end")
+	}
+
+	return window, nil
+}
+
+// active returns true if the time is inside the maintenance window
+func (w *maintenanceWindow) active(now time.Time) bool {
+	if w.schedule == nil {
+		return !now.Before(w.start) && now.Before(w.end)
+	}
+
+	// the window is active if it started during the last duration
+	lastStart := w.schedule.Next(now.Add(-w.duration))
+
+	return !lastStart.After(now)
+}
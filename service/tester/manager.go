@@ -2,33 +2,132 @@ package tester
 
 import (
 	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/go-multierror"
+	"github.com/orensho/thin-slack-blackbox-tester/service/browser"
 	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
 	"github.com/orensho/thin-slack-blackbox-tester/service/service"
 	"github.com/orensho/thin-slack-blackbox-tester/service/steps"
 	"github.com/pkg/errors"
 	"github.com/robfig/cron/v3"
+	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	ErrFlowNotFound = errors.New("flow not found")
+	ErrFlowRunning  = errors.New("flow is already running")
+	ErrNoRuns       = errors.New("flow did not run yet")
+)
+
+// pausedReportInterval is how often the paused flows gauge is updated, maintenance windows start and end on their own
+const pausedReportInterval = 15 * time.Second
+
 type ManagerInterface interface {
 	Init(conf *config.TesterConfig) error
+	// Reload applies a new configuration, only added, removed or changed flows are rescheduled.
+	// When the configuration is invalid an error is returned and the current flows keep running
+	Reload(conf *config.TesterConfig) error
 	Start()
 	Stop()
+
+	// Flows returns the scheduled flows sorted by name
+	Flows() []FlowInfo
+	// GetFlow returns the scheduled flow
+	GetFlow(name string) (*FlowInfo, error)
+	// PauseFlow stops the flow scheduled runs until it is resumed
+	PauseFlow(name string) error
+	// ResumeFlow restarts the scheduled runs of a paused flow
+	ResumeFlow(name string) error
+	// RunFlow runs the flow now, when wait is false the returned result only holds the run id
+	RunFlow(name string, wait bool) (*RunResult, error)
+	// RunFlows runs the flows once, all the flows when no names are given, and returns
+	// their results in the flows order. Flows run concurrently unless serial is set
+	RunFlows(names []string, serial bool) ([]*RunResult, error)
+	// LatestRun returns the result of the latest (or in progress) run of the flow
+	LatestRun(name string) (*RunResult, error)
+}
+
+// scheduledFlow is a flow and its cron entry
+type scheduledFlow struct {
+	flow                *flow
+	definition          config.Flow
+	notifierDefinitions []config.Definition
+	schedule            cron.Schedule
+	entryID             cron.EntryID
+	browserPoolKey      string
+}
+
+// equals returns true if both flows have the same configuration, steps and notifiers definitions
+func (s *scheduledFlow) equals(other *scheduledFlow) bool {
+	if !reflect.DeepEqual(s.definition, other.definition) {
+		return false
+	}
+
+	if !reflect.DeepEqual(s.notifierDefinitions, other.notifierDefinitions) {
+		return false
+	}
+
+	return stepsEqual(s.flow.setup, other.flow.setup) &&
+		stepsEqual(s.flow.steps, other.flow.steps) &&
+		stepsEqual(s.flow.teardown, other.flow.teardown)
+}
+
+// stepsEqual returns true if both steps lists have the same steps definitions
+func stepsEqual(a []*flowStep, b []*flowStep) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i, step := range a {
+		if !reflect.DeepEqual(step.definition, b[i].definition) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sharedBrowserPool is a browser pool shared by the flows with the same browser,
+// it is stopped once no flow references it
+type sharedBrowserPool struct {
+	pool browser.PoolInterface
+	refs int
 }
 
 type managerImpl struct {
-	testerSettings *config.TesterSettings
-	stepsFactory   steps.StepFactoryInterface
-	cron           *cron.Cron
-	testContext    context.Context
-	testCancel     context.CancelFunc
-	metricsService service.MetricsServiceInterface
+	testerSettings  *config.TesterSettings
+	stepsFactory    steps.StepFactoryInterface
+	notifierFactory notifier.NotifierFactoryInterface
+	runHistory      RunHistoryInterface
+	reporter        RunReporterInterface
+	cron            *cron.Cron
+	testContext     context.Context
+	testCancel      context.CancelFunc
+	metricsService  service.MetricsServiceInterface
+
+	browserPoolsLock sync.Mutex
+	browserPools     map[string]*sharedBrowserPool // by remote browser URL and chrome flags
+	started          bool
+
+	flowsLock  sync.RWMutex
+	flows      map[string]*scheduledFlow
+	manualRuns sync.WaitGroup
+
+	notificationSends sync.WaitGroup
 }
 
 func NewManager(
 	rootCtx context.Context,
 	stepsFactory steps.StepFactoryInterface,
+	notifierFactory notifier.NotifierFactoryInterface,
+	runHistory RunHistoryInterface, // nil to disable the run history
+	reporter RunReporterInterface, // nil to disable the reports
 	testerSettings *config.TesterSettings,
 	metricsService service.MetricsServiceInterface,
 ) ManagerInterface {
@@ -36,20 +135,39 @@ func NewManager(
 	cronLogger := cron.PrintfLogger(log.StandardLogger())
 
 	return &managerImpl{
-		testerSettings: testerSettings,
-		metricsService: metricsService,
-		stepsFactory:   stepsFactory,
+		testerSettings:  testerSettings,
+		metricsService:  metricsService,
+		stepsFactory:    stepsFactory,
+		notifierFactory: notifierFactory,
+		runHistory:      runHistory,
+		reporter:        reporter,
 		cron: cron.New(cron.WithChain(
 			cron.Recover(cronLogger),
 			cron.SkipIfStillRunning(cronLogger),
 		)),
 		testContext: testContext,
 		testCancel:  testCancel,
+		flows:       map[string]*scheduledFlow{},
+
+		browserPools: map[string]*sharedBrowserPool{},
 	}
 }
 
 func (m *managerImpl) Start() {
+	m.browserPoolsLock.Lock()
+	m.started = true
+	for _, shared := range m.browserPools {
+		shared.pool.Start()
+	}
+	m.browserPoolsLock.Unlock()
+
 	m.cron.Start()
+
+	go m.reportPausedFlows()
+
+	if m.testerSettings.ArtifactsFolder != "" && m.testerSettings.ParsedArtifactsRetention > 0 {
+		go m.pruneArtifacts()
+	}
 }
 
 func (m *managerImpl) Stop() {
@@ -59,63 +177,584 @@ func (m *managerImpl) Stop() {
 	// stop the cron
 	doneCtx := m.cron.Stop()
 
-	// wait for cron jobs to stop
+	// wait for cron jobs and manual runs to stop
 	<-doneCtx.Done()
+	m.manualRuns.Wait()
+
+	// wait for the notifications of the last runs, each send is bounded by the notification timeout
+	m.notificationSends.Wait()
+
+	// close the shared browsers once no flow uses them
+	m.browserPoolsLock.Lock()
+	defer m.browserPoolsLock.Unlock()
+
+	for key, shared := range m.browserPools {
+		shared.pool.Stop()
+		delete(m.browserPools, key)
+	}
 }
 
 func (m *managerImpl) Init(conf *config.TesterConfig) error {
-	// create all test flows
+	flows, err := m.createFlows(conf)
+	if err != nil {
+		return err
+	}
+
+	m.flowsLock.Lock()
+	defer m.flowsLock.Unlock()
+
+	// add all flows to the scheduler
+	for flowName, scheduled := range flows {
+		scheduled.entryID = m.cron.Schedule(scheduled.schedule, scheduled.flow)
+		m.flows[flowName] = scheduled
+	}
+
+	return nil
+}
+
+func (m *managerImpl) Reload(conf *config.TesterConfig) error {
+	// create all flows before changing the scheduler so an invalid configuration doesn't change anything
+	flows, err := m.createFlows(conf)
+	if err != nil {
+		return err
+	}
+
+	m.flowsLock.Lock()
+	defer m.flowsLock.Unlock()
+
+	// remove deleted flows, running flows are not stopped
+	for flowName, scheduled := range m.flows {
+		if _, ok := flows[flowName]; !ok {
+			m.cron.Remove(scheduled.entryID)
+			delete(m.flows, flowName)
+			scheduled.flow.setPaused(false)
+			m.reportFlowPaused(scheduled.flow)
+			m.retireFlow(scheduled, nil)
+			log.WithField("flow", flowName).Infof("flow %s removed", flowName)
+		}
+	}
+
+	for flowName, scheduled := range flows {
+		current, ok := m.flows[flowName]
+		if ok && current.equals(scheduled) {
+			// the new flow is dropped, it never ran
+			m.releaseBrowserPool(scheduled.browserPoolKey)
+			continue
+		}
+
+		if ok {
+			// keep the flow runtime state, a run in progress blocks the runs of the new flow
+			// and its result is the latest run of the new flow
+			m.cron.Remove(current.entryID)
+			scheduled.flow.state = current.flow.state
+			m.retireFlow(current, scheduled)
+			log.WithField("flow", flowName).Infof("flow %s changed", flowName)
+		} else {
+			log.WithField("flow", flowName).Infof("flow %s added", flowName)
+		}
+
+		scheduled.entryID = m.cron.Schedule(scheduled.schedule, scheduled.flow)
+		m.flows[flowName] = scheduled
+	}
+
+	return nil
+}
+
+// createFlows creates all the configured flows, all the flows errors are returned
+func (m *managerImpl) createFlows(conf *config.TesterConfig) (map[string]*scheduledFlow, error) {
+	// the whole config is validated first to report all the errors with their position
+	findings := ValidateConfig(conf, m.stepsFactory, m.notifierFactory)
+	for _, warning := range findings.Warnings() {
+		log.Warn(warning.Error())
+	}
+	if err := findings.Err(); err != nil {
+		return nil, err
+	}
+
+	var flowsErr *multierror.Error
+	flows := make(map[string]*scheduledFlow, len(conf.Flows))
+
+	notifiers, err := m.createNotifiers(conf.Notifiers)
+	if err != nil {
+		flowsErr = multierror.Append(flowsErr, err)
+	}
+
 	for flowName, flowDefinition := range conf.Flows {
-		// create flow steps
-		flowSteps, err := m.createFlowSteps(conf.Definitions, flowDefinition.Steps)
+		scheduled, err := m.createFlow(conf, notifiers, flowName, flowDefinition)
 		if err != nil {
-			return errors.Wrapf(err, "Failed creating flow '%s' steps", flowName)
+			flowsErr = multierror.Append(flowsErr, err)
+			continue
+		}
+
+		flows[flowName] = scheduled
+	}
+
+	if err := flowsErr.ErrorOrNil(); err != nil {
+		// the created flows are dropped, release their browser pools
+		for _, scheduled := range flows {
+			m.releaseBrowserPool(scheduled.browserPoolKey)
+		}
+		return nil, err
+	}
+
+	return flows, nil
+}
+
+func (m *managerImpl) createFlow(
+	conf *config.TesterConfig,
+	notifiers map[string]notifier.NotifierInterface,
+	flowName string,
+	flowDefinition config.Flow,
+) (_ *scheduledFlow, err error) {
+	// create flow steps
+	setupSteps, err := m.createFlowSteps(conf.Definitions, flowDefinition.Setup)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed creating flow '%s' setup steps", flowName)
+	}
+
+	flowSteps, err := m.createFlowSteps(conf.Definitions, flowDefinition.Steps)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed creating flow '%s' steps", flowName)
+	}
+
+	teardownSteps, err := m.createFlowSteps(conf.Definitions, flowDefinition.Teardown)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed creating flow '%s' teardown steps", flowName)
+	}
+
+	browserPool, browserPoolKey, err := m.flowBrowserPool(flowDefinition.Config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed creating flow '%s'", flowName)
+	}
+	defer func() {
+		if err != nil {
+			m.releaseBrowserPool(browserPoolKey)
+		}
+	}()
+
+	// create the flow
+	flow, err := newFlow(
+		m.testContext,
+		flowName,
+		flowDefinition.Config,
+		setupSteps,
+		flowSteps,
+		teardownSteps,
+		m.stepsFactory,
+		m.testerSettings,
+		m.metricsService,
+		browserPool,
+		notifiers,
+		&m.notificationSends,
+		m.runHistory,
+		m.reporter,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed creating flow '%s'", flowName)
+	}
+
+	var notifierDefinitions []config.Definition
+	if flowDefinition.Config.Notifications != nil {
+		for _, notifierName := range flowDefinition.Config.Notifications.Notifiers {
+			notifierDefinitions = append(notifierDefinitions, conf.Notifiers[notifierName])
+		}
+	}
+
+	schedule, err := cron.ParseStandard(flowDefinition.Config.Frequency)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed scheduling flow '%s'", flowName)
+	}
+
+	return &scheduledFlow{
+		flow:                flow,
+		definition:          flowDefinition,
+		notifierDefinitions: notifierDefinitions,
+		schedule:            schedule,
+		browserPoolKey:      browserPoolKey,
+	}, nil
+}
+
+// flowBrowserPool returns the browser pool of the flow and its key, every remote browser
+// and every set of chrome flags has its own pool. The pool is referenced until
+// releaseBrowserPool is called with its key
+func (m *managerImpl) flowBrowserPool(conf config.FlowConfig) (browser.PoolInterface, string, error) {
+	remoteURL := m.testerSettings.BrowserRemoteURL
+	var flags map[string]interface{}
+	if conf.Browser != nil {
+		if conf.Browser.Remote != "" {
+			remoteURL = conf.Browser.Remote
+		}
+		flags = conf.Browser.Flags
+	}
+
+	if remoteURL != "" {
+		if len(flags) > 0 {
+			return nil, "", errors.New("chrome flags cannot be set on a remote browser")
+		}
+
+		var err error
+		remoteURL, err = browser.RemoteURL(remoteURL)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	key := remoteURL + "|" + browser.FlagsKey(flags)
+
+	m.browserPoolsLock.Lock()
+	defer m.browserPoolsLock.Unlock()
+
+	shared, ok := m.browserPools[key]
+	if !ok {
+		shared = &sharedBrowserPool{
+			pool: browser.NewPool(m.testContext, remoteURL, flags, m.testerSettings, m.metricsService),
+		}
+		m.browserPools[key] = shared
+
+		// pools of flows added by a reload
+		if m.started {
+			shared.pool.Start()
+		}
+	}
+	shared.refs++
+
+	return shared.pool, key, nil
+}
+
+// releaseBrowserPool releases a flow reference to the browser pool, the pool is stopped
+// once no flow references it
+func (m *managerImpl) releaseBrowserPool(key string) {
+	m.browserPoolsLock.Lock()
+	defer m.browserPoolsLock.Unlock()
+
+	// the pools are all stopped when the manager stops
+	shared, ok := m.browserPools[key]
+	if !ok {
+		return
+	}
+
+	shared.refs--
+	if shared.refs > 0 {
+		return
+	}
+
+	delete(m.browserPools, key)
+	shared.pool.Stop()
+	log.WithField("pool", key).Info("stopped unused browser pool")
+}
+
+// retireFlow releases the browser pool of a removed or replaced flow once its run in progress ends,
+// the notifications state of a replaced flow is then copied so its last run alerts are kept
+func (m *managerImpl) retireFlow(scheduled *scheduledFlow, replacement *scheduledFlow) {
+	scheduled.flow.retire(func() {
+		if replacement != nil && replacement.flow.notifications != nil && scheduled.flow.notifications != nil {
+			replacement.flow.notifications.copyState(scheduled.flow.notifications)
 		}
+		m.releaseBrowserPool(scheduled.browserPoolKey)
+	})
+}
+
+// createNotifiers creates all the configured notifiers by name
+func (m *managerImpl) createNotifiers(notifiersDefinition map[string]config.Definition) (map[string]notifier.NotifierInterface, error) { //nolint // line length
+	var notifiersErr *multierror.Error
+	notifiers := make(map[string]notifier.NotifierInterface, len(notifiersDefinition))
 
-		// create the flow
-		flow, err := newFlow(
-			m.testContext,
-			flowName,
-			flowDefinition.Config,
-			flowSteps,
-			m.metricsService,
-		)
+	for notifierName, notifierDefinition := range notifiersDefinition {
+		flowNotifier, err := m.notifierFactory.NewNotifier(notifierDefinition.Type)
 		if err != nil {
-			return errors.Wrapf(err, "Failed creating flow '%s'", flowName)
+			notifiersErr = multierror.Append(notifiersErr, errors.Wrapf(err, "Failed creating notifier '%s'", notifierName))
+			continue
 		}
 
-		// add flow to the scheduler
-		_, err = m.cron.AddJob(flowDefinition.Config.Frequency, flow)
+		err = flowNotifier.Init(notifierName, notifierDefinition.Config)
 		if err != nil {
-			return errors.Wrapf(err, "Failed scheduling flow '%s'", flowName)
+			notifiersErr = multierror.Append(notifiersErr, errors.Wrapf(err, "Failed initializing notifier '%s'", notifierName))
+			continue
 		}
+
+		notifiers[notifierName] = flowNotifier
+	}
+
+	return notifiers, notifiersErr.ErrorOrNil()
+}
+
+func (m *managerImpl) Flows() []FlowInfo {
+	m.flowsLock.RLock()
+	defer m.flowsLock.RUnlock()
+
+	flows := make([]FlowInfo, 0, len(m.flows))
+	for name, scheduled := range m.flows {
+		flows = append(flows, m.flowInfo(name, scheduled))
+	}
+
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].Name < flows[j].Name
+	})
+
+	return flows
+}
+
+func (m *managerImpl) GetFlow(name string) (*FlowInfo, error) {
+	m.flowsLock.RLock()
+	defer m.flowsLock.RUnlock()
+
+	scheduled, ok := m.flows[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrFlowNotFound, "flow '%s'", name)
+	}
+
+	info := m.flowInfo(name, scheduled)
+
+	return &info, nil
+}
+
+func (m *managerImpl) flowInfo(name string, scheduled *scheduledFlow) FlowInfo {
+	info := FlowInfo{
+		Name:          name,
+		Schedule:      scheduled.definition.Config.Frequency,
+		Setup:         config.StepNames(scheduled.definition.Setup),
+		Steps:         config.StepNames(scheduled.definition.Steps),
+		Teardown:      config.StepNames(scheduled.definition.Teardown),
+		Paused:        scheduled.flow.isPaused(),
+		InMaintenance: scheduled.flow.inMaintenance(time.Now()),
+		LastRun:       scheduled.flow.LastResult(),
+	}
+
+	// the next run is zero until the cron is started
+	if next := m.cron.Entry(scheduled.entryID).Next; !next.IsZero() {
+		info.NextRun = &next
+	}
+
+	return info
+}
+
+func (m *managerImpl) PauseFlow(name string) error {
+	return m.setFlowPaused(name, true)
+}
+
+func (m *managerImpl) ResumeFlow(name string) error {
+	return m.setFlowPaused(name, false)
+}
+
+func (m *managerImpl) setFlowPaused(name string, paused bool) error {
+	f, err := m.getFlow(name)
+	if err != nil {
+		return err
 	}
 
+	f.setPaused(paused)
+	log.WithField("flow", name).Infof("flow %s paused: %t", name, paused)
+
+	m.reportFlowPaused(f)
+
 	return nil
 }
 
-func (m *managerImpl) createFlowSteps(stepsDefinition map[string]config.Definition, flowStepNames []string) ([]steps.StepInterface, error) {
-	var flowSteps []steps.StepInterface
+// reportPausedFlows updates the paused flows gauge until the manager is stopped
+func (m *managerImpl) reportPausedFlows() {
+	ticker := time.NewTicker(pausedReportInterval)
+	defer ticker.Stop()
 
-	for _, stepName := range flowStepNames {
+	for {
+		m.flowsLock.RLock()
+		for _, scheduled := range m.flows {
+			m.reportFlowPaused(scheduled.flow)
+		}
+		m.flowsLock.RUnlock()
+
+		select {
+		case <-m.testContext.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneArtifacts deletes the failed runs artifacts older than the retention periodically
+func (m *managerImpl) pruneArtifacts() {
+	ticker := time.NewTicker(artifactsPruneInterval)
+	defer ticker.Stop()
+
+	retention := m.testerSettings.ParsedArtifactsRetention
+	for {
+		pruned, err := pruneArtifacts(m.testerSettings.ArtifactsFolder, time.Now().Add(-retention))
+		if err != nil {
+			log.WithError(err).Error("failed pruning run artifacts")
+		}
+		if pruned > 0 {
+			log.Infof("pruned the artifacts of %d runs older than %s", pruned, retention)
+		}
+
+		select {
+		case <-m.testContext.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *managerImpl) reportFlowPaused(f *flow) {
+	paused := f.isPaused() || f.inMaintenance(time.Now())
+
+	err := m.metricsService.ReportFlowPaused(m.testContext, f.name, paused)
+	if err != nil {
+		log.WithField("flow", f.name).WithError(err).Error("failed reporting flow paused")
+	}
+}
+
+func (m *managerImpl) RunFlow(name string, wait bool) (*RunResult, error) {
+	f, err := m.getFlow(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.tryStart() {
+		return nil, ErrFlowRunning
+	}
+
+	runID := uuid.NewV4()
+	log.WithField("flow", name).WithField("runId", runID).Infof("running flow %s on demand", name)
+
+	m.manualRuns.Add(1)
+	run := func() *RunResult {
+		defer m.manualRuns.Done()
+		defer f.done()
+
+		return f.run(runID)
+	}
+
+	if wait {
+		return run(), nil
+	}
+
+	go run()
+
+	return &RunResult{
+		RunID:     runID.String(),
+		Flow:      name,
+		Status:    StatusRunning,
+		StartTime: time.Now(),
+		Steps:     []*StepResult{},
+	}, nil
+}
+
+func (m *managerImpl) RunFlows(names []string, serial bool) ([]*RunResult, error) {
+	if len(names) == 0 {
+		for _, info := range m.Flows() {
+			names = append(names, info.Name)
+		}
+	}
+
+	// check all the flows exist before running any of them
+	var flowsErr *multierror.Error
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if _, err := m.getFlow(name); err != nil {
+			flowsErr = multierror.Append(flowsErr, err)
+			continue
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	if err := flowsErr.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+
+	results := make([]*RunResult, len(unique))
+	errs := make([]error, len(unique))
+	var wg sync.WaitGroup
+	for i, name := range unique {
+		run := func(i int, name string) {
+			defer wg.Done()
+			results[i], errs[i] = m.RunFlow(name, true)
+		}
+
+		wg.Add(1)
+		if serial {
+			run(i, name)
+		} else {
+			go run(i, name)
+		}
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		flowsErr = multierror.Append(flowsErr, err)
+	}
+
+	return results, flowsErr.ErrorOrNil()
+}
+
+func (m *managerImpl) LatestRun(name string) (*RunResult, error) {
+	f, err := m.getFlow(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := f.LastResult()
+	if result == nil {
+		return nil, ErrNoRuns
+	}
+
+	return result, nil
+}
+
+func (m *managerImpl) getFlow(name string) (*flow, error) {
+	m.flowsLock.RLock()
+	defer m.flowsLock.RUnlock()
+
+	scheduled, ok := m.flows[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrFlowNotFound, "flow '%s'", name)
+	}
+
+	return scheduled.flow, nil
+}
+
+func (m *managerImpl) createFlowSteps(stepsDefinition map[string]config.Definition, flowStepsConfig []config.FlowStep) ([]*flowStep, error) { //nolint // line length
+	var flowSteps []*flowStep
+
+	for _, stepConfig := range flowStepsConfig {
+		stepName := stepConfig.Step
 		stepDefinition, ok := stepsDefinition[stepName]
 		if !ok {
 			return nil, errors.Errorf("undefined step '%s'", stepName)
 		}
 
+		policy, err := newStepPolicy(stepConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid step '%s' policy", stepName)
+		}
+
 		// create the step
 		step, err := m.stepsFactory.NewStep(stepDefinition.Type)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed creating step '%s'", stepName)
 		}
 
+		// templated steps are initialized on every run with the flow variables
+		if steps.IsTemplated(stepDefinition.Config) {
+			flowSteps = append(flowSteps, &flowStep{name: stepName, definition: stepDefinition, policy: policy, needsBrowser: step.NeedsBrowser()}) //nolint // line length
+			continue
+		}
+
 		// init the step configuration
 		err = step.Init(stepName, stepDefinition.Config)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed initializing step '%s'", stepName)
 		}
 
-		flowSteps = append(flowSteps, step)
+		flowSteps = append(flowSteps, &flowStep{
+			name:         stepName,
+			definition:   stepDefinition,
+			policy:       policy,
+			needsBrowser: step.NeedsBrowser(),
+			step:         step,
+		})
 	}
 
 	return flowSteps, nil
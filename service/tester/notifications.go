@@ -0,0 +1,149 @@
+package tester
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	notificationTimeout     = 30 * time.Second
+	defaultFailureThreshold = 1
+)
+
+// flowNotifications decides when a flow run result should be notified: once the flow failed
+// failureThreshold times in a row, again every repeatInterval while it keeps failing
+// and once when it recovers
+type flowNotifications struct {
+	notifiers        []notifier.NotifierInterface
+	failureThreshold int
+	recovery         bool
+	repeatInterval   time.Duration
+
+	lock                sync.Mutex
+	consecutiveFailures int
+	alerting            bool
+	lastSent            time.Time
+}
+
+func newFlowNotifications(
+	conf *config.NotificationsConfig,
+	notifiers map[string]notifier.NotifierInterface,
+) (*flowNotifications, error) {
+	n := &flowNotifications{
+		failureThreshold: defaultFailureThreshold,
+		recovery:         true,
+	}
+
+	for _, name := range conf.Notifiers {
+		flowNotifier, ok := notifiers[name]
+		if !ok {
+			return nil, errors.Errorf("undefined notifier '%s'", name)
+		}
+		n.notifiers = append(n.notifiers, flowNotifier)
+	}
+
+	if conf.FailureThreshold < 0 {
+		return nil, errors.Errorf("invalid failure threshold %d", conf.FailureThreshold)
+	}
+	if conf.FailureThreshold > 0 {
+		n.failureThreshold = conf.FailureThreshold
+	}
+
+	if conf.Recovery != nil {
+		n.recovery = *conf.Recovery
+	}
+
+	if conf.RepeatInterval != "" {
+		repeatInterval, err := time.ParseDuration(conf.RepeatInterval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing repeat interval '%s'", conf.RepeatInterval)
+		}
+		n.repeatInterval = repeatInterval
+	}
+
+	return n, nil
+}
+
+// copyState keeps the failures count and alert state of the flow when its configuration is reloaded
+func (n *flowNotifications) copyState(other *flowNotifications) {
+	other.lock.Lock()
+	defer other.lock.Unlock()
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.consecutiveFailures = other.consecutiveFailures
+	n.alerting = other.alerting
+	n.lastSent = other.lastSent
+}
+
+// notificationKind returns the kind of notification to send for the run result, empty for none
+func (n *flowNotifications) notificationKind(result *RunResult, now time.Time) string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if result.Passed() {
+		n.consecutiveFailures = 0
+		if !n.alerting {
+			return ""
+		}
+
+		n.alerting = false
+		if !n.recovery {
+			return ""
+		}
+
+		return notifier.KindRecovery
+	}
+
+	n.consecutiveFailures++
+	if n.consecutiveFailures < n.failureThreshold {
+		return ""
+	}
+
+	// dedup failures of the same incident
+	if n.alerting && (n.repeatInterval == 0 || now.Sub(n.lastSent) < n.repeatInterval) {
+		return ""
+	}
+
+	n.alerting = true
+	n.lastSent = now
+
+	return notifier.KindFailure
+}
+
+func (n *flowNotifications) failures() int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.consecutiveFailures
+}
+
+// send notifies all the flow notifiers in the background, the sends are added to sends
+// so they can be waited for, each within the notification timeout
+func (n *flowNotifications) send(logger *log.Entry, notification *notifier.Notification, sends *sync.WaitGroup) {
+	for _, flowNotifier := range n.notifiers {
+		sends.Add(1)
+		go func(flowNotifier notifier.NotifierInterface) {
+			defer sends.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+			defer cancel()
+
+			notifierLogger := logger.WithField("notifier", flowNotifier.GetName())
+			err := flowNotifier.Notify(ctx, notification)
+			if err != nil {
+				notifierLogger.WithError(err).Errorf("failed sending %s notification", notification.Kind)
+				return
+			}
+
+			notifierLogger.Infof("sent %s notification", notification.Kind)
+		}(flowNotifier)
+	}
+}
@@ -0,0 +1,198 @@
+package tester
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/notifier"
+	log "github.com/sirupsen/logrus"
+)
+
+// slowNotifier counts the notifications it sent after a delay
+type slowNotifier struct {
+	delay time.Duration
+	sent  int32
+}
+
+func (n *slowNotifier) GetType() string { return "slow" }
+
+func (n *slowNotifier) GetName() string { return "slow" }
+
+func (n *slowNotifier) Init(name string, conf map[string]interface{}) error { return nil }
+
+func (n *slowNotifier) Notify(ctx context.Context, notification *notifier.Notification) error {
+	select {
+	case <-time.After(n.delay):
+		atomic.AddInt32(&n.sent, 1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestFlowNotificationsKind(t *testing.T) {
+	noRecovery := false
+
+	// every run is a minute after the previous one
+	type run struct {
+		status string
+		kind   string
+	}
+
+	tests := []struct {
+		name string
+		conf config.NotificationsConfig
+		runs []run
+	}{
+		{
+			name: "failure and recovery",
+			conf: config.NotificationsConfig{},
+			runs: []run{
+				{StatusSuccess, ""},
+				{StatusError, notifier.KindFailure},
+				{StatusSuccess, notifier.KindRecovery},
+				{StatusSuccess, ""},
+			},
+		},
+		{
+			name: "threshold",
+			conf: config.NotificationsConfig{FailureThreshold: 3},
+			runs: []run{
+				{StatusError, ""},
+				{StatusTimeout, ""},
+				{StatusError, notifier.KindFailure},
+				{StatusError, ""},
+				{StatusWarning, notifier.KindRecovery},
+			},
+		},
+		{
+			name: "recovery below threshold is not notified",
+			conf: config.NotificationsConfig{FailureThreshold: 2},
+			runs: []run{
+				{StatusError, ""},
+				{StatusSuccess, ""},
+				{StatusError, ""},
+				{StatusError, notifier.KindFailure},
+			},
+		},
+		{
+			name: "dedup without repeat interval",
+			conf: config.NotificationsConfig{},
+			runs: []run{
+				{StatusError, notifier.KindFailure},
+				{StatusError, ""},
+				{StatusError, ""},
+				{StatusSuccess, notifier.KindRecovery},
+				{StatusError, notifier.KindFailure},
+			},
+		},
+		{
+			name: "repeat interval",
+			conf: config.NotificationsConfig{RepeatInterval: "2m"},
+			runs: []run{
+				{StatusError, notifier.KindFailure},
+				{StatusError, ""},
+				{StatusError, notifier.KindFailure},
+				{StatusError, ""},
+			},
+		},
+		{
+			name: "recovery disabled",
+			conf: config.NotificationsConfig{Recovery: &noRecovery},
+			runs: []run{
+				{StatusError, notifier.KindFailure},
+				{StatusSuccess, ""},
+				{StatusError, notifier.KindFailure},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := newFlowNotifications(&tt.conf, nil)
+			if err != nil {
+				t.Fatalf("failed creating notifications: %v", err)
+			}
+
+			now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, r := range tt.runs {
+				kind := n.notificationKind(&RunResult{Status: r.status}, now)
+				if kind != r.kind {
+					t.Fatalf("run %d %s: expected notification %q got %q", i, r.status, r.kind, kind)
+				}
+				now = now.Add(time.Minute)
+			}
+		})
+	}
+}
+
+func TestFlowNotificationsCopyState(t *testing.T) {
+	current, err := newFlowNotifications(&config.NotificationsConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed creating notifications: %v", err)
+	}
+
+	now := time.Now()
+	if kind := current.notificationKind(&RunResult{Status: StatusError}, now); kind != notifier.KindFailure {
+		t.Fatalf("expected a failure notification got %q", kind)
+	}
+
+	// the reloaded flow keeps alerting, the same incident is not notified again
+	reloaded, err := newFlowNotifications(&config.NotificationsConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed creating notifications: %v", err)
+	}
+	reloaded.copyState(current)
+
+	if kind := reloaded.notificationKind(&RunResult{Status: StatusError}, now); kind != "" {
+		t.Fatalf("expected no notification got %q", kind)
+	}
+	if failures := reloaded.failures(); failures != 2 {
+		t.Fatalf("expected 2 consecutive failures got %d", failures)
+	}
+	if kind := reloaded.notificationKind(&RunResult{Status: StatusSuccess}, now); kind != notifier.KindRecovery {
+		t.Fatalf("expected a recovery notification got %q", kind)
+	}
+}
+
+func TestNewFlowNotificationsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		conf config.NotificationsConfig
+	}{
+		{"undefined notifier", config.NotificationsConfig{Notifiers: []string{"missing"}}},
+		{"negative threshold", config.NotificationsConfig{FailureThreshold: -1}},
+		{"invalid repeat interval", config.NotificationsConfig{RepeatInterval: "often"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newFlowNotifications(&tt.conf, nil); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestFlowNotificationsSendIsWaited(t *testing.T) {
+	slow := &slowNotifier{delay: 50 * time.Millisecond}
+	notifications, err := newFlowNotifications(
+		&config.NotificationsConfig{Notifiers: []string{"a", "b"}},
+		map[string]notifier.NotifierInterface{"a": slow, "b": slow},
+	)
+	if err != nil {
+		t.Fatalf("failed creating notifications: %v", err)
+	}
+
+	var sends sync.WaitGroup
+	notifications.send(log.NewEntry(log.StandardLogger()), &notifier.Notification{Kind: notifier.KindFailure}, &sends)
+	sends.Wait()
+
+	if sent := atomic.LoadInt32(&slow.sent); sent != 2 {
+		t.Fatalf("expected 2 sent notifications got %d", sent)
+	}
+}
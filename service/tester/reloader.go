@@ -0,0 +1,173 @@
+package tester
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/orensho/thin-slack-blackbox-tester/service/service"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// reloadDebounce groups the burst of file events an editor or a config map update creates into a single reload
+const reloadDebounce = time.Second
+
+type ConfigReloaderInterface interface {
+	// Start reloads the config on SIGHUP and, when enabled, on config file changes
+	Start() error
+	Stop()
+	// Reload loads the config file and applies it to the manager
+	Reload() error
+}
+
+type configReloaderImpl struct {
+	configReader   config.BlackboxConfigReader
+	testerSettings *config.TesterSettings
+	manager        ManagerInterface
+	metricsService service.MetricsServiceInterface
+
+	reloadLock sync.Mutex
+	watcher    *fsnotify.Watcher
+	stopChan   chan struct{}
+	stopped    sync.WaitGroup
+}
+
+func NewConfigReloader(
+	configReader config.BlackboxConfigReader,
+	testerSettings *config.TesterSettings,
+	manager ManagerInterface,
+	metricsService service.MetricsServiceInterface,
+) ConfigReloaderInterface {
+	return &configReloaderImpl{
+		configReader:   configReader,
+		testerSettings: testerSettings,
+		manager:        manager,
+		metricsService: metricsService,
+		stopChan:       make(chan struct{}),
+	}
+}
+
+func (r *configReloaderImpl) Start() error {
+	if r.testerSettings.WatchConfig {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return errors.Wrap(err, "failed creating config watcher")
+		}
+
+		// the folder is watched as editors and config maps replace the file instead of writing it
+		configFolder := filepath.Dir(r.testerSettings.ConfigFilePath())
+		err = watcher.Add(configFolder)
+		if err != nil {
+			_ = watcher.Close()
+			return errors.Wrapf(err, "failed watching config folder %s", configFolder)
+		}
+		r.watcher = watcher
+
+		r.stopped.Add(1)
+		go r.watchConfigFile()
+	}
+
+	r.stopped.Add(1)
+	go r.watchSignal()
+
+	return nil
+}
+
+func (r *configReloaderImpl) Stop() {
+	close(r.stopChan)
+	if r.watcher != nil {
+		_ = r.watcher.Close()
+	}
+
+	r.stopped.Wait()
+}
+
+func (r *configReloaderImpl) Reload() error {
+	r.reloadLock.Lock()
+	defer r.reloadLock.Unlock()
+
+	logger := log.WithField("config", r.testerSettings.ConfigFilePath())
+	logger.Info("reloading tester config")
+
+	testerConfig, err := r.configReader.LoadTesterConfig(r.testerSettings)
+	if err == nil {
+		err = r.manager.Reload(testerConfig)
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("failed reloading tester config, keeping the current config")
+
+		errReport := r.metricsService.ReportConfigReloadFailure(context.Background())
+		if errReport != nil {
+			logger.WithError(errReport).Error("failed reporting config reload failure")
+		}
+
+		return errors.Wrap(err, "failed reloading tester config")
+	}
+
+	errReport := r.metricsService.ReportConfigReloadSuccess(context.Background())
+	if errReport != nil {
+		logger.WithError(errReport).Error("failed reporting config reload success")
+	}
+	logger.Info("tester config reloaded")
+
+	return nil
+}
+
+func (r *configReloaderImpl) watchSignal() {
+	defer r.stopped.Done()
+
+	hupSig := make(chan os.Signal, 1)
+	signal.Notify(hupSig, syscall.SIGHUP)
+	defer signal.Stop(hupSig)
+
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case <-hupSig:
+			log.Info("Received SIGHUP - reloading config.")
+			_ = r.Reload()
+		}
+	}
+}
+
+func (r *configReloaderImpl) watchConfigFile() {
+	defer r.stopped.Done()
+
+	configFile := filepath.Base(r.testerSettings.ConfigFilePath())
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			debounce.Stop()
+			return
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+
+			// config maps swap a '..data' symlink instead of changing the file
+			name := filepath.Base(event.Name)
+			if name == configFile || name == "..data" {
+				debounce.Reset(reloadDebounce)
+			}
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithError(err).Error("config watcher error")
+		case <-debounce.C:
+			_ = r.Reload()
+		}
+	}
+}
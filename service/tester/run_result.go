@@ -0,0 +1,140 @@
+package tester
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusError   = "error"
+	StatusTimeout = "timeout"
+	StatusSkipped = "skipped"
+	// StatusWarning is a step which failed with continueOnError, or a flow which passed with such steps
+	StatusWarning = "warning"
+)
+
+// RunResult is the outcome of a single flow run
+type RunResult struct {
+	RunID      string        `json:"runId"`
+	Flow       string        `json:"flow"`
+	Status     string        `json:"status"`
+	StartTime  time.Time     `json:"startTime"`
+	EndTime    *time.Time    `json:"endTime,omitempty"`
+	DurationMS float64       `json:"durationMs"`
+	Error      string        `json:"error,omitempty"`
+	Artifacts  string        `json:"artifacts,omitempty"` // link to the artifacts captured on failure
+	Setup      []*StepResult `json:"setup,omitempty"`
+	Steps      []*StepResult `json:"steps"`
+
+	// the teardown result is apart from the run status so cleanup failures do not mask the run failure
+	Teardown       []*StepResult `json:"teardown,omitempty"`
+	TeardownStatus string        `json:"teardownStatus,omitempty"`
+	TeardownError  string        `json:"teardownError,omitempty"`
+}
+
+// RunHistoryInterface persists the finished flow runs
+type RunHistoryInterface interface {
+	Save(result *RunResult) error
+}
+
+// RunReporterInterface writes the finished flow runs reports
+type RunReporterInterface interface {
+	Report(results []*RunResult) error
+}
+
+// StepResult is the outcome of a single step in a flow run
+type StepResult struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"durationMs"`
+	Attempts   int     `json:"attempts,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// FlowInfo describes a scheduled flow
+type FlowInfo struct {
+	Name          string     `json:"name"`
+	Schedule      string     `json:"schedule"`
+	Setup         []string   `json:"setup,omitempty"`
+	Steps         []string   `json:"steps"`
+	Teardown      []string   `json:"teardown,omitempty"`
+	Paused        bool       `json:"paused"`
+	InMaintenance bool       `json:"inMaintenance"`
+	NextRun       *time.Time `json:"nextRun,omitempty"`
+	LastRun       *RunResult `json:"lastRun,omitempty"`
+}
+
+func (r *RunResult) finish(status string, err error) {
+	endTime := time.Now()
+	r.EndTime = &endTime
+	r.DurationMS = float64(endTime.Sub(r.StartTime).Nanoseconds()) / 1e6
+	r.Status = status
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// mainSteps returns the setup and flow steps results
+func (r *RunResult) mainSteps() []*StepResult {
+	stepResults := make([]*StepResult, 0, len(r.Setup)+len(r.Steps))
+	stepResults = append(stepResults, r.Setup...)
+
+	return append(stepResults, r.Steps...)
+}
+
+// failedStep returns the step which stopped the run, nil if no step failed
+func (r *RunResult) failedStep() *StepResult {
+	for _, step := range r.mainSteps() {
+		if step.Status == StatusError || step.Status == StatusTimeout {
+			return step
+		}
+	}
+
+	return nil
+}
+
+// Passed returns true if the run finished without a failing step
+func (r *RunResult) Passed() bool {
+	return r.Status == StatusSuccess || r.Status == StatusWarning
+}
+
+// warnings returns the errors of the steps which failed with continueOnError
+func (r *RunResult) warnings() error {
+	var warnings *multierror.Error
+	for _, step := range r.mainSteps() {
+		if step.Status == StatusWarning {
+			warnings = multierror.Append(warnings, errors.Errorf("step '%s' failed: %s", step.Name, step.Error))
+		}
+	}
+
+	return warnings.ErrorOrNil()
+}
+
+// copy returns a deep copy of the result, safe to read while the original is updated
+func (r *RunResult) copy() *RunResult {
+	c := *r
+	c.Setup = copySteps(r.Setup)
+	c.Steps = copySteps(r.Steps)
+	c.Teardown = copySteps(r.Teardown)
+
+	return &c
+}
+
+func copySteps(stepResults []*StepResult) []*StepResult {
+	if stepResults == nil {
+		return nil
+	}
+
+	c := make([]*StepResult, 0, len(stepResults))
+	for _, step := range stepResults {
+		stepCopy := *step
+		c = append(c, &stepCopy)
+	}
+
+	return c
+}
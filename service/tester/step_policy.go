@@ -0,0 +1,48 @@
+package tester
+
+import (
+	"time"
+
+	"github.com/orensho/thin-slack-blackbox-tester/service/config"
+	"github.com/pkg/errors"
+)
+
+const defaultRetryBackoff = time.Second
+
+// stepPolicy is how a flow runs one of its steps
+type stepPolicy struct {
+	timeout         time.Duration // zero when only the flow timeout applies
+	retries         int
+	backoff         time.Duration // delay before the first retry, doubled on every retry
+	continueOnError bool
+}
+
+func newStepPolicy(conf config.FlowStep) (stepPolicy, error) {
+	policy := stepPolicy{
+		retries:         conf.Retries,
+		backoff:         defaultRetryBackoff,
+		continueOnError: conf.ContinueOnError,
+	}
+
+	if conf.Retries < 0 {
+		return policy, errors.Errorf("invalid retries %d", conf.Retries)
+	}
+
+	if conf.Timeout != nil {
+		timeout, err := time.ParseDuration(*conf.Timeout)
+		if err != nil {
+			return policy, errors.Wrapf(err, "failed parsing timeout '%s'", *conf.Timeout)
+		}
+		policy.timeout = timeout
+	}
+
+	if conf.Backoff != nil {
+		backoff, err := time.ParseDuration(*conf.Backoff)
+		if err != nil {
+			return policy, errors.Wrapf(err, "failed parsing backoff '%s'", *conf.Backoff)
+		}
+		policy.backoff = backoff
+	}
+
+	return policy, nil
+}